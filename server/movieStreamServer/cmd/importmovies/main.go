@@ -0,0 +1,86 @@
+// Command importmovies bulk-loads movies from a CSV or NDJSON file using the
+// same validation and upsert rules as POST /admin/movies/import.
+//
+//	go run ./cmd/importmovies -file movies.csv
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+func main() {
+	file := flag.String("file", "", "path to the CSV or NDJSON file to import")
+	format := flag.String("format", "", "csv or ndjson (default: from the file extension)")
+	verbose := flag.Bool("v", false, "print the result of every row, not only rejected ones")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".csv":
+			*format = utils.FormatCSV
+		case ".ndjson", ".jsonl":
+			*format = utils.FormatNDJSON
+		default:
+			log.Fatalf("Cannot tell the format of %s, pass -format", *file)
+		}
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("Unable to find .env file")
+	}
+	dbName := os.Getenv("DATABASE_NAME")
+
+	if err := database.DBinstance(os.Getenv("MONGODB_URI")); err != nil {
+		log.Fatalf("Mongo connection failed: %v", err)
+	}
+	defer database.Client.Disconnect(context.Background())
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	report, err := utils.ImportMovies(f, *format, dbName)
+	if err != nil {
+		log.Fatalf("Import failed after %d inserted, %d updated, %d rejected: %v", report.Inserted, report.Updated, report.Rejected, err)
+	}
+
+	if err := utils.RecordAudit("cli", utils.AuditMoviesImported, "", map[string]interface{}{
+		modelStructs.ImportInserted: report.Inserted,
+		modelStructs.ImportUpdated:  report.Updated,
+		modelStructs.ImportRejected: report.Rejected,
+		"file":                      filepath.Base(*file),
+	}, dbName); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, row := range report.Rows {
+		if *verbose || row.Status == modelStructs.ImportRejected {
+			enc.Encode(row)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "inserted %d, updated %d, rejected %d\n", report.Inserted, report.Updated, report.Rejected)
+
+	if report.Rejected > 0 {
+		database.Client.Disconnect(context.Background())
+		os.Exit(1)
+	}
+}
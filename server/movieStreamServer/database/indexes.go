@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// EnsureIndexes creates the indexes the server relies on. Creating an index
+// that already exists with the same options is a no-op in MongoDB.
+func EnsureIndexes(dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"refresh_tokens": {
+			{Keys: bson.M{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"family_id": 1}},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"sessions": {
+			{Keys: bson.M{"session_id": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"user_id": 1}},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"signing_keys": {
+			{Keys: bson.M{"kid": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"audit_log": {
+			{Keys: bson.M{"target_id": 1}},
+			{Keys: bson.M{"created_at": -1}},
+		},
+		"login_attempts": {
+			{Keys: bson.M{"key": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"password_resets": {
+			{Keys: bson.M{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"user_id": 1}},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"login_challenges": {
+			{Keys: bson.M{"token_hash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"users": {
+			{Keys: primitive.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}},
+		},
+		"oidc_states": {
+			{Keys: bson.M{"state_hash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"api_keys": {
+			{Keys: bson.M{"key_id": 1}, Options: options.Index().SetUnique(true)},
+		},
+		"movies": {
+			{Keys: bson.M{"imdb_id": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: primitive.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: primitive.D{{Key: "ranking.ranking_value", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.M{"genre.genre_id": 1}},
+			{
+				Keys: primitive.D{{Key: "title", Value: "text"}, {Key: "admin_review", Value: "text"}, {Key: "genre.genre_name", Value: "text"}},
+				Options: options.Index().SetName("movie_text").
+					SetWeights(bson.M{"title": 10, "genre.genre_name": 5, "admin_review": 1}),
+			},
+		},
+		"idempotency_keys": {
+			{Keys: bson.M{"key": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"genres": {
+			{Keys: bson.M{"genre_id": 1}, Options: options.Index().SetUnique(true)},
+			{
+				Keys: bson.M{"genre_name": 1},
+				Options: options.Index().SetUnique(true).
+					SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+			},
+		},
+		"rankings": {
+			{Keys: bson.M{"ranking_value": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"ranking_name": 1}, Options: options.Index().SetUnique(true)},
+		},
+		"revoked_tokens": {
+			{Keys: bson.M{"jti": 1}},
+			{Keys: bson.M{"session_id": 1}},
+			{Keys: bson.M{"user_id": 1}},
+			{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	}
+
+	for name, models := range indexes {
+		if _, err := OpenCollection(name, dbName).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Migrate backfills fields introduced after documents were first written so
+// that older records behave like new ones. Every step is idempotent.
+func Migrate(dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Accounts created before email verification existed are trusted as-is.
+	_, err := OpenCollection("users", dbName).UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := moveDuplicateMovies(ctx, dbName); err != nil {
+		return err
+	}
+
+	if err := seedGenres(ctx, dbName); err != nil {
+		return err
+	}
+
+	if err := backfillRankings(ctx, dbName); err != nil {
+		return err
+	}
+
+	// Movies written before updated_at was tracked take their creation time
+	// from the ObjectID.
+	if _, err := OpenCollection("movies", dbName).UpdateMany(ctx,
+		bson.M{"updated_at": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"updated_at": bson.M{"$toDate": "$_id"}}}}},
+	); err != nil {
+		return err
+	}
+
+	// Every existing movie starts at version 1 so it has an ETag to match.
+	_, err = OpenCollection("movies", dbName).UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 1}},
+	)
+	return err
+}
+
+// backfillRankings gives ranking levels written before the scale could be
+// managed an order and a hidden flag. The old 999 sentinel for unranked
+// movies becomes a hidden level.
+func backfillRankings(ctx context.Context, dbName string) error {
+	rankings := OpenCollection("rankings", dbName)
+
+	if _, err := rankings.UpdateMany(ctx,
+		bson.M{"hidden": bson.M{"$exists": false}, "ranking_value": 999},
+		bson.M{"$set": bson.M{"hidden": true}},
+	); err != nil {
+		return err
+	}
+	if _, err := rankings.UpdateMany(ctx,
+		bson.M{"hidden": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"hidden": false}},
+	); err != nil {
+		return err
+	}
+
+	_, err := rankings.UpdateMany(ctx,
+		bson.M{"order": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"order": "$ranking_value"}}}},
+	)
+	return err
+}
+
+// seedGenres fills an empty genres collection from the genres embedded in
+// movies and users. When the same ID or name appears more than once the
+// first one by ID wins, matching the unique indexes on the collection, and
+// the references to the losing entry are rewritten to the winner so nothing
+// is left pointing at a genre the catalog does not have.
+func seedGenres(ctx context.Context, dbName string) error {
+	genres := OpenCollection("genres", dbName)
+
+	count, err := genres.CountDocuments(ctx, bson.M{})
+	if err != nil || count > 0 {
+		return err
+	}
+
+	var seen []modelStructs.Genre
+	for _, source := range []struct{ collection, field string }{
+		{"movies", "$genre"},
+		{"users", "$favorite_genres"},
+	} {
+		pipeline := mongo.Pipeline{
+			{{Key: "$unwind", Value: source.field}},
+			{{Key: "$replaceRoot", Value: bson.M{"newRoot": source.field}}},
+		}
+
+		cursor, err := OpenCollection(source.collection, dbName).Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+
+		var batch []modelStructs.Genre
+		err = cursor.All(ctx, &batch)
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+		seen = append(seen, batch...)
+	}
+
+	sort.SliceStable(seen, func(i, j int) bool { return seen[i].GenreID < seen[j].GenreID })
+
+	ids := map[int]modelStructs.Genre{}
+	names := map[string]modelStructs.Genre{}
+	var docs []interface{}
+	var conflicts []modelStructs.Genre
+	winners := map[modelStructs.Genre]modelStructs.Genre{}
+	addConflict := func(from, to modelStructs.Genre) {
+		if _, ok := winners[from]; !ok {
+			winners[from] = to
+			conflicts = append(conflicts, from)
+		}
+	}
+	for _, genre := range seen {
+		if genre.GenreID == 0 || genre.GenreName == "" {
+			log.Printf("Genre migration: skipping incomplete genre %+v", genre)
+			continue
+		}
+		if winner, ok := ids[genre.GenreID]; ok {
+			if winner.GenreName != genre.GenreName {
+				addConflict(genre, winner)
+			}
+			continue
+		}
+		if winner, ok := names[strings.ToLower(genre.GenreName)]; ok {
+			addConflict(genre, winner)
+			continue
+		}
+		ids[genre.GenreID] = genre
+		names[strings.ToLower(genre.GenreName)] = genre
+		docs = append(docs, genre)
+	}
+
+	for _, from := range conflicts {
+		to := winners[from]
+		log.Printf("Genre migration: rewriting %d:%q to %d:%q", from.GenreID, from.GenreName, to.GenreID, to.GenreName)
+		if err := rewriteGenre(ctx, dbName, from, to); err != nil {
+			return err
+		}
+	}
+
+	if len(docs) == 0 {
+		return nil
+	}
+	_, err = genres.InsertMany(ctx, docs)
+	return err
+}
+
+// rewriteGenre points the movie and user references to from at to instead.
+// Lists that already hold to just lose from, so no list ends up with the same
+// genre twice.
+func rewriteGenre(ctx context.Context, dbName string, from, to modelStructs.Genre) error {
+	now := time.Now().UTC()
+
+	for _, target := range []struct {
+		collection, field string
+		set               bson.M
+		inc               bson.M
+	}{
+		{"movies", "genre", bson.M{"updated_at": now}, bson.M{"version": 1}},
+		{"users", "favorite_genres", bson.M{"updated_at": now}, nil},
+	} {
+		collection := OpenCollection(target.collection, dbName)
+		idField := target.field + ".genre_id"
+		match := bson.M{target.field: bson.M{"$elemMatch": bson.M{"genre_id": from.GenreID, "genre_name": from.GenreName}}}
+
+		update := func(change bson.M) bson.M {
+			for k, v := range target.set {
+				change["$set"].(bson.M)[k] = v
+			}
+			if target.inc != nil {
+				change["$inc"] = target.inc
+			}
+			return change
+		}
+
+		// A differently named copy of the same ID only needs its name fixed.
+		if from.GenreID == to.GenreID {
+			if _, err := collection.UpdateMany(ctx, match,
+				update(bson.M{"$set": bson.M{target.field + ".$[g].genre_name": to.GenreName}}),
+				options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+					bson.M{"g.genre_id": from.GenreID, "g.genre_name": from.GenreName},
+				}}),
+			); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := collection.UpdateMany(ctx,
+			bson.M{"$and": bson.A{match, bson.M{idField: to.GenreID}}},
+			update(bson.M{
+				"$pull": bson.M{target.field: bson.M{"genre_id": from.GenreID, "genre_name": from.GenreName}},
+				"$set":  bson.M{},
+			}),
+		); err != nil {
+			return err
+		}
+
+		if _, err := collection.UpdateMany(ctx, match,
+			update(bson.M{"$set": bson.M{target.field + ".$[g]": to}}),
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+				bson.M{"g.genre_id": from.GenreID, "g.genre_name": from.GenreName},
+			}}),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveDuplicateMovies keeps the oldest movie for each imdb_id and moves the
+// other copies into movies_duplicates so the unique imdb_id index can be
+// built. Nothing is deleted; the copies can be reviewed and merged by hand.
+func moveDuplicateMovies(ctx context.Context, dbName string) error {
+	movies := OpenCollection("movies", dbName)
+	duplicates := OpenCollection("movies_duplicates", dbName)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$group", Value: bson.M{"_id": "$imdb_id", "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := movies.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		for _, id := range group.IDs[1:] {
+			var doc primitive.M
+			if err := movies.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+				return err
+			}
+			if _, err := duplicates.ReplaceOne(ctx, bson.M{"_id": id}, doc, options.Replace().SetUpsert(true)); err != nil {
+				return err
+			}
+			if _, err := movies.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (cfg Config) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+
+	filter := bson.M{}
+	if role := query.Get("role"); role != "" {
+		filter["role"] = role
+	}
+	if email := query.Get("email"); email != "" {
+		filter["email"] = email
+	}
+
+	limit, skip := int64(50), int64(0)
+	if v, err := strconv.ParseInt(query.Get("limit"), 10, 64); err == nil && v > 0 && v <= 200 {
+		limit = v
+	}
+	if v, err := strconv.ParseInt(query.Get("skip"), 10, 64); err == nil && v > 0 {
+		skip = v
+	}
+
+	users := make([]modelStructs.UserSummary, 0)
+	collection := database.OpenCollection("users", cfg.DbName)
+
+	findOptions := options.Find().SetSort(bson.M{"created_at": 1}).SetSkip(skip).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching users: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &users); err != nil {
+		http.Error(w, fmt.Sprintf("Error Cursor:%s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+func (cfg Config) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	userId := r.PathValue("user_id")
+
+	var req modelStructs.RoleUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if userId == actorId {
+		http.Error(w, "Admins cannot change their own role", http.StatusConflict)
+		return
+	}
+
+	previous, err := cfg.updateUser(r.Context(), userId, bson.M{"role": req.Role})
+	if err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	// Tokens carry the role, so force the user through /refresh to pick up
+	// the new one.
+	if err := utils.RevokeUserTokens(userId, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error revoking tokens: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	details := map[string]interface{}{"from": previous.Role, "to": req.Role}
+	if err := utils.RecordAudit(actorId, utils.AuditRoleChanged, userId, details, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg Config) DisableUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setUserDisabled(w, r, true)
+}
+
+func (cfg Config) EnableUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setUserDisabled(w, r, false)
+}
+
+func (cfg Config) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	userId := r.PathValue("user_id")
+
+	if userId == actorId {
+		http.Error(w, "Admins cannot disable their own account", http.StatusConflict)
+		return
+	}
+
+	if _, err := cfg.updateUser(r.Context(), userId, bson.M{"disabled": disabled}); err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	action := utils.AuditUserEnabled
+	if disabled {
+		action = utils.AuditUserDisabled
+
+		if err := cfg.signOutEverywhere(userId); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := utils.RecordAudit(actorId, action, userId, nil, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetUserCredentials replaces the user's password with a random temporary
+// one and signs them out everywhere. The temporary password is returned once
+// so the admin can hand it over.
+func (cfg Config) ResetUserCredentials(w http.ResponseWriter, r *http.Request) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	userId := r.PathValue("user_id")
+
+	password, err := utils.MakeTemporaryPassword()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error generating password: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error hashing password: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := cfg.updateUser(r.Context(), userId, bson.M{"password": hashedPassword}); err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	if err := cfg.signOutEverywhere(userId); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.RecordAudit(actorId, utils.AuditCredentialsReset, userId, nil, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(modelStructs.CredentialReset{
+		UserID:            userId,
+		TemporaryPassword: password,
+	})
+}
+
+func (cfg Config) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	userId := r.PathValue("user_id")
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+	if err := collection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	if err := utils.UnlockLogin(user.Email, user.UserID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error unlocking user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.RecordAudit(actorId, utils.AuditUserUnlocked, userId, nil, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg Config) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := int64(100)
+	if v, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+
+	entries, err := utils.GetAuditLog(r.URL.Query().Get("target_id"), limit, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// updateUser applies set to the user and returns the document as it was
+// before the update.
+func (cfg Config) updateUser(ctx context.Context, userId string, set bson.M) (*modelStructs.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	set["updated_at"] = time.Now().UTC()
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, bson.M{"$set": set}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func writeUpdateUserError(w http.ResponseWriter, err error) {
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error updating user: %v", err), http.StatusInternalServerError)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+func (cfg Config) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+
+	var req modelStructs.APIKeyRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !utils.IsPermission(scope) {
+			http.Error(w, fmt.Sprintf("Unknown scope: %s", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	created, err := utils.CreateAPIKey(req.Name, req.Scopes, actorId, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating API key: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	details := map[string]interface{}{"name": req.Name, "scopes": req.Scopes}
+	if err := utils.RecordAudit(actorId, utils.AuditAPIKeyCreated, created.KeyID, details, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (cfg Config) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := utils.ListAPIKeys(cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching API keys: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (cfg Config) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	keyId := r.PathValue("key_id")
+
+	found, err := utils.RevokeAPIKey(keyId, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error revoking API key: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if err := utils.RecordAudit(actorId, utils.AuditAPIKeyRevoked, keyId, nil, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// exportFlushEvery is how many movies are written between flushes, which
+// also pushes the write deadline forward.
+const exportFlushEvery = 500
+
+// exportStatusTrailer is sent as "complete" after the last movie. A stream
+// without it was cut short.
+const exportStatusTrailer = "X-Export-Status"
+
+// ExportMovies streams the catalog as NDJSON or CSV, one movie at a time, so
+// memory use does not grow with the collection. It accepts the same filters
+// as GetMovieHandler. CSV output can be fed back into the importer.
+//
+// Errors after the first byte cannot change the status code, so a failed
+// export ends with an {"error": ...} record in NDJSON and the connection is
+// aborted, which also keeps the X-Export-Status trailer from being sent.
+func (cfg Config) ExportMovies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = utils.FormatNDJSON
+	}
+	if format != utils.FormatCSV && format != utils.FormatNDJSON {
+		http.Error(w, fmt.Sprintf("Unsupported export format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := movieFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	collection := database.OpenCollection("movies", cfg.DbName)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching movies: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	rc := http.NewResponseController(w)
+	extendDeadline := func() {
+		rc.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	}
+	extendDeadline()
+
+	filename := "movies-" + time.Now().UTC().Format("20060102") + "." + format
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Trailer", exportStatusTrailer)
+
+	var write func(modelStructs.Movie) error
+	var flush func() error
+	var writeError func(error)
+
+	switch format {
+	case utils.FormatCSV:
+		csvWriter := csv.NewWriter(w)
+		write = func(movie modelStructs.Movie) error {
+			return csvWriter.Write([]string{
+				movie.ImdbID,
+				movie.Title,
+				movie.PosterPath,
+				movie.YouTubeID,
+				utils.FormatGenreList(movie.Genre),
+				strconv.Itoa(movie.Ranking.RankingValue),
+				movie.Ranking.RankingName,
+				movie.AdminReview,
+				movie.AdminScore,
+			})
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+		writeError = func(error) {}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := csvWriter.Write(utils.MovieCSVColumns); err != nil {
+			return
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		write = func(movie modelStructs.Movie) error {
+			return encoder.Encode(movie)
+		}
+		flush = func() error { return nil }
+		writeError = func(err error) {
+			encoder.Encode(map[string]string{"error": err.Error()})
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+
+	// abort ends a failed export. http.ErrAbortHandler makes the server
+	// drop the connection without logging a stack trace.
+	abort := func(stage string, err error) {
+		log.Printf("Export stopped, %s: %v", stage, err)
+		panic(http.ErrAbortHandler)
+	}
+	fail := func(stage string, err error) {
+		writeError(fmt.Errorf("export stopped, %s", stage))
+		flush()
+		rc.Flush()
+		abort(stage, err)
+	}
+
+	count := 0
+	for cursor.Next(ctx) {
+		var movie modelStructs.Movie
+		if err := cursor.Decode(&movie); err != nil {
+			fail("decoding movie", err)
+		}
+		if err := write(movie); err != nil {
+			abort("writing movie", err)
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				abort("writing movie", err)
+			}
+			rc.Flush()
+			extendDeadline()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		fail("reading movies", err)
+	}
+	if err := flush(); err != nil {
+		abort("writing movie", err)
+	}
+	w.Header().Set(exportStatusTrailer, "complete")
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (cfg Config) ListGenres(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	genres := make([]modelStructs.Genre, 0)
+	collection := database.OpenCollection("genres", cfg.DbName)
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"genre_name": 1}))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching genres: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &genres); err != nil {
+		http.Error(w, fmt.Sprintf("Error Cursor:%s", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeCachedJSON(w, r, genres, catalogCacheControl, "", time.Time{})
+}
+
+func (cfg Config) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var genre modelStructs.Genre
+
+	if err := json.NewDecoder(r.Body).Decode(&genre); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(genre); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	collection := database.OpenCollection("genres", cfg.DbName)
+	if _, err := collection.InsertOne(ctx, genre); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A genre with this ID or name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error adding genre: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(genre)
+}
+
+// UpdateGenre renames a genre everywhere it is embedded.
+func (cfg Config) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	genreId, err := strconv.Atoi(r.PathValue("genre_id"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	var req modelStructs.GenreUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.RenameGenre(genreId, req.GenreName, cfg.DbName); err != nil {
+		writeGenreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(modelStructs.Genre{GenreID: genreId, GenreName: req.GenreName})
+}
+
+func (cfg Config) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	genreId, err := strconv.Atoi(r.PathValue("genre_id"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.DeleteGenre(genreId, cfg.DbName); err != nil {
+		writeGenreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeGenreError(w http.ResponseWriter, err error) {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		http.Error(w, "Genre not found", http.StatusNotFound)
+	case errors.Is(err, utils.ErrGenreInUse), mongo.IsDuplicateKeyError(err):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Error updating genre: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+const maxImportSize = 32 << 20
+
+// ImportMovies bulk-upserts movies from a CSV or NDJSON body. The format is
+// taken from the format query parameter or else the Content-Type header.
+func (cfg Config) ImportMovies(w http.ResponseWriter, r *http.Request) {
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = utils.FormatCSV
+		case "application/x-ndjson", "application/ndjson":
+			format = utils.FormatNDJSON
+		}
+	}
+	if format != utils.FormatCSV && format != utils.FormatNDJSON {
+		http.Error(w, "Unsupported import format, send text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Large catalogs take longer than the server-wide timeouts allow.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(5 * time.Minute))
+	rc.SetWriteDeadline(time.Now().Add(5 * time.Minute))
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	defer body.Close()
+
+	// The report goes back even when the import stops early, since the rows
+	// before the failure have already been written.
+	status := http.StatusOK
+	report, err := utils.ImportMovies(body, format, cfg.DbName)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, utils.ErrInvalidImport):
+			status = http.StatusBadRequest
+		default:
+			status = http.StatusInternalServerError
+		}
+		report.Error = fmt.Sprintf("Error importing movies: %v", err)
+	}
+
+	if report.Inserted+report.Updated > 0 || err == nil {
+		details := map[string]interface{}{
+			modelStructs.ImportInserted: report.Inserted,
+			modelStructs.ImportUpdated:  report.Updated,
+			modelStructs.ImportRejected: report.Rejected,
+		}
+		if err := utils.RecordAudit(actorId, utils.AuditMoviesImported, "", details, cfg.DbName); err != nil {
+			http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (cfg Config) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cfg.Keys.JWKS())
+}
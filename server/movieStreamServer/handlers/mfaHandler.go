@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (cfg Config) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.findUser(r.Context(), r.Context().Value(utils.UserIDKey).(string))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error generating secret: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.BeginTOTPEnrollment(user.UserID, secret, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error starting enrollment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(modelStructs.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(secret, user.Email),
+	})
+}
+
+// ConfirmTOTP switches two-factor authentication on once the user proves
+// their authenticator produces valid codes, and hands out recovery codes.
+func (cfg Config) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req modelStructs.TOTPConfirm
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	claims := r.Context().Value(utils.ClaimsKey).(*utils.AccessTokenClaims)
+
+	user, err := cfg.findUser(r.Context(), claims.Subject)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.TOTPPendingSecret == "" {
+		http.Error(w, "No two-factor enrollment in progress", http.StatusConflict)
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPPendingSecret, req.Code, 0, time.Now())
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(utils.RecoveryCodeCount)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error generating recovery codes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.EnableTOTP(user.UserID, user.TOTPPendingSecret, step, hashes, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error enabling two-factor authentication: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Other devices signed in with just a password; make them log in again.
+	if err := utils.DeleteUserSessions(user.UserID, claims.SessionID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error signing out sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(modelStructs.RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (cfg Config) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var req modelStructs.TOTPDisable
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.findUser(r.Context(), r.Context().Value(utils.UserIDKey).(string))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if !user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	if cfg.RequireAdmin2FA && user.Role == "ADMIN" {
+		http.Error(w, "Two-factor authentication is required for admin accounts", http.StatusForbidden)
+		return
+	}
+
+	// Both factors are throttled like a login so a stolen access token
+	// cannot be used to brute-force either of them.
+	ip := utils.ClientIP(r)
+	wait, err := utils.ReserveLoginAttempt(user.Email, ip, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := utils.CheckPasswordAndHash(req.Password, user.Password); err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	wait, err = utils.ReserveTwoFactorAttempt(user.UserID, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(*user, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking code: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ReleaseLoginAttempt(user.Email, ip, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ClearTwoFactorFailures(user.UserID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.DisableTOTP(user.UserID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error disabling two-factor authentication: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginTwoFactor completes a login that LoginUser answered with a challenge.
+func (cfg Config) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req modelStructs.TwoFactorLogin
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := utils.GetLoginChallenge(req.ChallengeToken, cfg.DbName)
+	if err != nil {
+		if err == utils.ErrInvalidChallenge {
+			http.Error(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error finding login challenge: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := cfg.findUser(r.Context(), challenge.UserID)
+	if err != nil || user.Disabled {
+		http.Error(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+		return
+	}
+
+	wait, err := utils.ReserveTwoFactorAttempt(user.UserID, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(*user, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking code: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		if err := utils.RecordChallengeFailure(req.ChallengeToken, cfg.DbName); err != nil {
+			http.Error(w, fmt.Sprintf("Error recording attempt: %v", err), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	completed, err := utils.CompleteLoginChallenge(req.ChallengeToken, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error completing login challenge: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !completed {
+		http.Error(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ClearTwoFactorFailures(user.UserID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	userRes, err := cfg.startSession(r, *user, challenge.DeviceName, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cfg.writeUserResponse(w, http.StatusCreated, userRes)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code, consuming whichever was presented.
+func (cfg Config) checkSecondFactor(user modelStructs.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return utils.UseRecoveryCode(user.UserID, recoveryCode, cfg.DbName)
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return utils.UseTOTPStep(user.UserID, step, cfg.DbName)
+}
+
+func (cfg Config) findUser(ctx context.Context, userId string) (*modelStructs.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+	if err := collection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
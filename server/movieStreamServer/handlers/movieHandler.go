@@ -3,54 +3,233 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 var validate = validator.New()
 
+type movieSortField struct {
+	field string
+	kind  utils.CursorKind
+}
+
+// movieSortFields maps the sort query parameter to the field it orders by
+// and the kind of value cursors carry for it. Prefix a name with "-" for
+// descending order.
+var movieSortFields = map[string]movieSortField{
+	"title":   {field: "title", kind: utils.CursorString},
+	"ranking": {field: "ranking.ranking_value", kind: utils.CursorInt},
+	"added":   {field: "_id", kind: utils.CursorNone},
+}
+
+// GetMovieHandler lists movies a page at a time. Pages are addressed by
+// opaque cursors so results stay stable while the catalog changes.
 func (cfg Config) GetMovieHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "title"
+	}
+	sortBy, ok := movieSortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Invalid sort: %s", sort), http.StatusBadRequest)
+		return
+	}
+	field := sortBy.field
+	desc := strings.HasPrefix(sort, "-")
+
+	var cursor *utils.PageCursor
+	if c := query.Get("cursor"); c != "" {
+		var err error
+		if cursor, err = utils.DecodeCursor(c, sort, sortBy.kind); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter, err := movieFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var movies []modelStructs.Movie
 	collection := database.OpenCollection("movies", cfg.DbName)
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	// The count and newest updated_at of the matching movies change whenever
+	// any page of the listing could, so they let a revalidation be answered
+	// without fetching and encoding the page. The newest updated_at alone is
+	// no Last-Modified: deleting a movie leaves it unchanged or moves it
+	// back, so listings are revalidated by ETag only.
+	total, updatedAt, err := movieListState(ctx, collection, filter)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Collection not found:%s", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Error counting movies: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &movies); err != nil {
-		http.Error(w, fmt.Sprintf("Error Cursor:%s", err), http.StatusInternalServerError)
+	etag := utils.ListETag(r.URL.Path+"?"+query.Encode(), total, updatedAt)
+	w.Header().Set("Cache-Control", catalogCacheControl)
+	w.Header().Set("ETag", etag)
+	if utils.NotModified(r, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	if err = json.NewEncoder(w).Encode(movies); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	pageFilter := filter
+	before := false
+	if cursor != nil {
+		pageFilter = bson.M{"$and": bson.A{filter, utils.KeysetFilter(field, desc, *cursor)}}
+		before = cursor.Before
+	}
+
+	limit := utils.PageSize(r)
+	findOptions := options.Find().SetSort(utils.KeysetSort(field, desc, before)).SetLimit(limit + 1)
+
+	movies := make([]modelStructs.Movie, 0)
+
+	cursorRes, err := collection.Find(ctx, pageFilter, findOptions)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching movies: %v", err), http.StatusInternalServerError)
 		return
 	}
+	defer cursorRes.Close(ctx)
+
+	if err = cursorRes.All(ctx, &movies); err != nil {
+		http.Error(w, fmt.Sprintf("Error Cursor:%s", err), http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := int64(len(movies)) > limit
+	if hasMore {
+		movies = movies[:limit]
+	}
+	if before {
+		slices.Reverse(movies)
+	}
+
+	page := modelStructs.MoviePage{Movies: movies, Total: total}
+	page.Next, page.Prev = pageLinks(r, cursor, hasMore, len(movies), func(i int, before bool) utils.PageCursor {
+		return utils.PageCursor{Sort: sort, Value: movieSortValue(movies[i], field), ID: movies[i].ID.Hex(), Before: before}
+	})
+
+	writeCachedJSON(w, r, page, catalogCacheControl, etag, time.Time{})
+}
+
+// movieListState returns how many movies match filter and when the most
+// recently changed of them was last updated.
+func movieListState(ctx context.Context, collection *mongo.Collection, filter bson.M) (int64, time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"total":      bson.M{"$sum": 1},
+			"updated_at": bson.M{"$max": "$updated_at"},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var state struct {
+		Total     int64     `bson:"total"`
+		UpdatedAt time.Time `bson:"updated_at"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&state); err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+	return state.Total, state.UpdatedAt, cursor.Err()
+}
+
+// movieFilter builds the filter for the genre, ranking and title_prefix
+// query parameters shared by the listing and search endpoints.
+func movieFilter(query url.Values) (bson.M, error) {
+	filter := bson.M{}
+	if v := query.Get("genre"); v != "" {
+		genreId, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid genre: %s", v)
+		}
+		filter["genre.genre_id"] = genreId
+	}
+	if v := query.Get("ranking"); v != "" {
+		ranking, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ranking: %s", v)
+		}
+		filter["ranking.ranking_value"] = ranking
+	}
+	if v := query.Get("title_prefix"); v != "" {
+		filter["title"] = bson.M{"$regex": "^" + regexp.QuoteMeta(v), "$options": "i"}
+	}
+	return filter, nil
+}
+
+func movieSortValue(movie modelStructs.Movie, field string) interface{} {
+	switch field {
+	case "title":
+		return movie.Title
+	case "ranking.ranking_value":
+		return movie.Ranking.RankingValue
+	}
+	return nil
+}
+
+// pageLinks builds the next and prev links for a page of n items that was
+// read from cursor. hasMore reports whether more items exist beyond the page
+// in the direction it was read; cursorAt returns the cursor for item i.
+func pageLinks(r *http.Request, cursor *utils.PageCursor, hasMore bool, n int, cursorAt func(i int, before bool) utils.PageCursor) (next, prev string) {
+	if n == 0 {
+		return "", ""
+	}
+
+	backwards := cursor != nil && cursor.Before
+	if hasMore || backwards {
+		next = pageLink(r, cursorAt(n-1, false))
+	}
+	if (backwards && hasMore) || (!backwards && cursor != nil) {
+		prev = pageLink(r, cursorAt(0, true))
+	}
+	return next, prev
+}
+
+func pageLink(r *http.Request, cursor utils.PageCursor) string {
+	query := r.URL.Query()
+	query.Set("cursor", utils.EncodeCursor(cursor))
+
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return link.String()
 }
 
 func (cfg Config) GetOneMovieHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	w.Header().Set("Content-Type", "application/json")
-
 	imdbID := r.PathValue("imdb_id")
 
 	var movie modelStructs.Movie
@@ -61,9 +240,41 @@ func (cfg Config) GetOneMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(movie); err != nil {
+	// The route requires a login, so shared caches must not store it.
+	writeCachedJSON(w, r, movie, "private, no-cache", utils.VersionETag(movie.Version), movie.UpdatedAt)
+}
+
+// catalogCacheControl lets clients and shared caches reuse public catalog
+// responses briefly and revalidate them with the ETag afterwards.
+const catalogCacheControl = "public, max-age=60, must-revalidate"
+
+// writeCachedJSON sends v as JSON along with validators for conditional
+// requests, answering 304 when the client already has the current
+// representation. An empty etag is derived from the encoded body.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, cacheControl, etag string, lastModified time.Time) {
+	body, err := json.Marshal(v)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if etag == "" {
+		etag = utils.BodyETag(body)
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if utils.NotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
 }
 
 func (cfg Config) AddMovie(w http.ResponseWriter, r *http.Request) {
@@ -84,27 +295,353 @@ func (cfg Config) AddMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if movie.Genre, err = utils.ResolveGenres(movie.Genre, cfg.DbName); err != nil {
+		writeResolveGenresError(w, err)
+		return
+	}
+	if movie.Ranking, err = utils.ResolveRanking(movie.Ranking, cfg.DbName); err != nil {
+		writeResolveRankingError(w, err)
+		return
+	}
+
+	movie.UpdatedAt = time.Now().UTC()
+	movie.Version = 1
+
 	collection := database.OpenCollection("movies", cfg.DbName)
 	res, err := collection.InsertOne(ctx, movie)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			cfg.writeExistingMovie(ctx, w, movie.ImdbID)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Error adding movie: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", utils.VersionETag(movie.Version))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(res)
 }
 
-func (cfg Config) AdminReview(w http.ResponseWriter, r *http.Request) {
+// UpdateMovie applies a partial update to a movie. Only the fields present in
+// the body change, and the result must still pass the Movie validation rules.
+func (cfg Config) UpdateMovie(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	imdbId := r.PathValue("imdb_id")
+
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(fields) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+	updatable := movieFieldValues(modelStructs.Movie{})
+	for field := range fields {
+		if _, ok := updatable[field]; !ok {
+			http.Error(w, fmt.Sprintf("Field cannot be updated: %s", field), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var movie modelStructs.Movie
+	collection := database.OpenCollection("movies", cfg.DbName)
+
+	if err := collection.FindOne(ctx, bson.M{"imdb_id": imdbId}).Decode(&movie); err != nil {
+		writeMovieError(w, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, movie) {
+		return
+	}
+
+	id, version := movie.ID, movie.Version
+	if err := json.Unmarshal(body, &movie); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	movie.ID, movie.Version = id, version
+
+	if err := validate.Struct(movie); err != nil {
+		http.Error(w, fmt.Sprintf("error: Validation failed, details: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := fields["genre"]; ok {
+		if movie.Genre, err = utils.ResolveGenres(movie.Genre, cfg.DbName); err != nil {
+			writeResolveGenresError(w, err)
+			return
+		}
+	}
+	if _, ok := fields["ranking"]; ok {
+		if movie.Ranking, err = utils.ResolveRanking(movie.Ranking, cfg.DbName); err != nil {
+			writeResolveRankingError(w, err)
+			return
+		}
+	}
+
+	if movie.ImdbID != imdbId {
+		count, err := collection.CountDocuments(ctx, bson.M{"imdb_id": movie.ImdbID})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error checking imdb_id: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			http.Error(w, fmt.Sprintf("A movie with imdb_id %s already exists", movie.ImdbID), http.StatusConflict)
+			return
+		}
+	}
+
+	movie.UpdatedAt = time.Now().UTC()
+
+	set := bson.M{"updated_at": movie.UpdatedAt}
+	for field, value := range movieFieldValues(movie) {
+		if _, ok := fields[field]; ok {
+			set[field] = value
+		}
+	}
 
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "version": version},
+		bson.M{"$set": set, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, fmt.Sprintf("A movie with imdb_id %s already exists", movie.ImdbID), http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error updating movie: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		writeLostUpdate(ctx, w, collection, id)
+		return
+	}
+	movie.Version++
+
+	fieldNames := make([]string, 0, len(fields))
+	for field := range fields {
+		fieldNames = append(fieldNames, field)
+	}
+	slices.Sort(fieldNames)
+
+	details := map[string]interface{}{"imdb_id": imdbId, "fields": fieldNames}
+	if err := utils.RecordAudit(actorId, utils.AuditMovieUpdated, id.Hex(), details, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.VersionETag(movie.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(movie)
+}
+
+func (cfg Config) DeleteMovie(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actorId := r.Context().Value(utils.UserIDKey).(string)
+	imdbId := r.PathValue("imdb_id")
+
+	var movie modelStructs.Movie
+
+	collection := database.OpenCollection("movies", cfg.DbName)
+	if err := collection.FindOne(ctx, bson.M{"imdb_id": imdbId}).Decode(&movie); err != nil {
+		writeMovieError(w, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, movie) {
+		return
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": movie.ID, "version": movie.Version})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error deleting movie: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		writeLostUpdate(ctx, w, collection, movie.ID)
+		return
+	}
+
+	details := map[string]interface{}{"imdb_id": imdbId, "title": movie.Title}
+	if err := utils.RecordAudit(actorId, utils.AuditMovieDeleted, movie.ID.Hex(), details, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error recording audit entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// movieFieldValues maps the fields UpdateMovie accepts, which share their
+// json and bson names, to their values in movie.
+func movieFieldValues(movie modelStructs.Movie) map[string]interface{} {
+	return map[string]interface{}{
+		"imdb_id":      movie.ImdbID,
+		"title":        movie.Title,
+		"poster_path":  movie.PosterPath,
+		"youtube_id":   movie.YouTubeID,
+		"genre":        movie.Genre,
+		"admin_score":  movie.AdminScore,
+		"admin_review": movie.AdminReview,
+		"ranking":      movie.Ranking,
+	}
+}
+
+// writeExistingMovie answers a duplicate insert with 409 and the movie that
+// already holds imdbId, so the caller can reconcile without another lookup.
+func (cfg Config) writeExistingMovie(ctx context.Context, w http.ResponseWriter, imdbId string) {
+	var existing modelStructs.Movie
+
+	collection := database.OpenCollection("movies", cfg.DbName)
+	if err := collection.FindOne(ctx, bson.M{"imdb_id": imdbId}).Decode(&existing); err != nil {
+		writeMovieError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.VersionETag(existing.Version))
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(existing)
+}
+
+// checkIfMatch requires the client to name the version of movie it based its
+// change on, so a write made from a stale copy cannot silently overwrite a
+// newer one. It answers 428 or 412 and returns false when the request must
+// stop. Callers still filter their write on the version to close the gap
+// between this check and the write.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, movie modelStructs.Movie) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		http.Error(w, "If-Match header with the movie's ETag is required", http.StatusPreconditionRequired)
+		return false
+	}
+	if !utils.ETagMatches(ifMatch, utils.VersionETag(movie.Version), false) {
+		writePreconditionFailed(w)
+		return false
+	}
+	return true
+}
+
+func writePreconditionFailed(w http.ResponseWriter) {
+	http.Error(w, "Movie was modified by someone else, fetch it again and retry", http.StatusPreconditionFailed)
+}
+
+// writeLostUpdate answers a version-checked write that matched nothing: the
+// movie was either deleted or changed since it was read.
+func writeLostUpdate(ctx context.Context, w http.ResponseWriter, collection *mongo.Collection, id primitive.ObjectID) {
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error finding movie: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+	writePreconditionFailed(w)
+}
+
+func writeResolveGenresError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrUnknownGenre) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error checking genres: %v", err), http.StatusInternalServerError)
+}
+
+func writeResolveRankingError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrUnknownRanking) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error checking ranking: %v", err), http.StatusInternalServerError)
+}
+
+func writeMovieError(w http.ResponseWriter, err error) {
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error fetching movie: %v", err), http.StatusInternalServerError)
+}
+
+// LookupMovieMetadata fetches details for imdb_id from the configured
+// metadata provider and returns them as a draft for an admin to review and
+// complete before calling AddMovie. Nothing is written to the catalog.
+func (cfg Config) LookupMovieMetadata(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	role := ctx.Value("role").(string)
-	if role != "ADMIN" {
-		http.Error(w, "Only Admins are allowed to add movie review", http.StatusUnauthorized)
+	if cfg.Metadata == nil {
+		http.Error(w, "No metadata provider is configured", http.StatusNotImplemented)
+		return
+	}
+
+	imdbId := r.PathValue("imdb_id")
+
+	collection := database.OpenCollection("movies", cfg.DbName)
+	count, err := collection.CountDocuments(ctx, bson.M{"imdb_id": imdbId})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking imdb_id: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		cfg.writeExistingMovie(ctx, w, imdbId)
+		return
+	}
+
+	movie, err := cfg.Metadata.LookupMovie(ctx, imdbId)
+	if err != nil {
+		if err == utils.ErrMetadataNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error fetching metadata: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	// Keep only genres AddMovie will accept and report the rest.
+	known, unknown, err := utils.SplitGenres(movie.Genre, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error resolving genres: %v", err), http.StatusInternalServerError)
 		return
 	}
+	movie.Genre = known
+
+	draft := modelStructs.MovieDraft{Movie: *movie, Missing: make([]string, 0), UnknownGenres: unknown}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(validate.Struct(movie), &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			draft.Missing = append(draft.Missing, fieldErr.Namespace())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (cfg Config) AdminReview(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
 
 	imdbId := r.PathValue("imdb_id")
 
@@ -118,13 +655,6 @@ func (cfg Config) AdminReview(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	llmRes, rankingValue, err := utils.GetReviewRanking(cfg.Genkit, cfg.BasePrompt, cfg.DbName, req.AdminReview)
-	if err != nil {
-		http.Error(w, "Error getting a review ranking", http.StatusInternalServerError)
-		log.Fatal(err)
-		return
-	}
-
 	collection := database.OpenCollection("movies", cfg.DbName)
 
 	var movie modelStructs.Movie
@@ -134,33 +664,49 @@ func (cfg Config) AdminReview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check before asking the model so a stale request costs nothing.
+	if !checkIfMatch(w, r, movie) {
+		return
+	}
+
+	ranking, err := utils.GetReviewRanking(cfg.Genkit, cfg.BasePrompt, cfg.DbName, req.AdminReview)
+	if errors.Is(err, utils.ErrRankingProvider) || errors.Is(err, utils.ErrUnknownRanking) {
+		http.Error(w, fmt.Sprintf("Error getting a review ranking: %v", err), http.StatusBadGateway)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting a review ranking: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	updateData := bson.M{
 		"$set": bson.M{
 			"admin_review": req.AdminReview,
-			"ranking": bson.M{
-				"ranking_value": rankingValue,
-				"ranking_name":  llmRes,
-			},
+			"updated_at":   time.Now().UTC(),
+			"ranking":      ranking,
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"imdb_id": imdbId}, updateData)
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": movie.ID, "version": movie.Version}, updateData)
 	if err != nil {
 		http.Error(w, "Error updating data", http.StatusInternalServerError)
 		return
 	}
 	if result.MatchedCount == 0 {
-		http.Error(w, "No movie found with the provided imdb ID", http.StatusBadRequest)
+		writeLostUpdate(ctx, w, collection, movie.ID)
 		return
 	}
 
+	w.Header().Set("ETag", utils.VersionETag(movie.Version+1))
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(struct {
 		RankingName string `json:"ranking_name"`
 		AdminReview string `json:"admin_review"`
 	}{
-		RankingName: req.AdminReview,
-		AdminReview: llmRes,
+		RankingName: ranking.RankingName,
+		AdminReview: req.AdminReview,
 	})
 }
 
@@ -168,9 +714,9 @@ func (cfg Config) GetRecommendations(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	userId := r.Context().Value("userID").(string)
+	userId := r.Context().Value(utils.UserIDKey).(string)
 
-	favGenres, err := utils.GetUserFavGenre(userId, cfg.DbName)
+	favGenres, err := utils.GetUserFavGenreIDs(userId, cfg.DbName)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Internal error: %v", err), http.StatusInternalServerError)
 		return
@@ -179,7 +725,7 @@ func (cfg Config) GetRecommendations(w http.ResponseWriter, r *http.Request) {
 	findOptions := options.Find().SetSort(bson.M{"ranking.ranking_value": 1}).SetLimit(cfg.MovieLimit)
 
 	collection := database.OpenCollection("movies", cfg.DbName)
-	cursor, err := collection.Find(ctx, bson.M{"genre.genre_name": bson.M{"$in": favGenres}}, findOptions)
+	cursor, err := collection.Find(ctx, bson.M{"genre.genre_id": bson.M{"$in": favGenres}}, findOptions)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error fetching recommended movies: %v", err), http.StatusInternalServerError)
 		return
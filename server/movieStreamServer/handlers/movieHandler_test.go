@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// testDatabase returns the name of a fresh database with the server's
+// indexes, dropped again when the test ends. Tests that need MongoDB are
+// skipped unless MONGODB_TEST_URI points at a server.
+func testDatabase(t *testing.T) string {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+	if database.Client == nil {
+		if err := database.DBinstance(uri); err != nil {
+			t.Fatalf("connecting to MongoDB: %v", err)
+		}
+	}
+
+	dbName := "movie_streamer_test_" + bson.NewObjectID().Hex()
+	if err := database.EnsureIndexes(dbName); err != nil {
+		t.Fatalf("creating indexes: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		database.Client.Database(dbName).Drop(ctx)
+	})
+	return dbName
+}
+
+func TestPageLinks(t *testing.T) {
+	ids := []string{"000000000000000000000001", "000000000000000000000002", "000000000000000000000003"}
+	titles := []string{"Alien", "Brazil", "Casablanca"}
+	cursorAt := func(i int, before bool) utils.PageCursor {
+		return utils.PageCursor{Sort: "title", Value: titles[i], ID: ids[i], Before: before}
+	}
+	forward := &utils.PageCursor{Sort: "title", ID: ids[0]}
+	backward := &utils.PageCursor{Sort: "title", ID: ids[0], Before: true}
+
+	tests := []struct {
+		name     string
+		cursor   *utils.PageCursor
+		hasMore  bool
+		n        int
+		wantNext *utils.PageCursor
+		wantPrev *utils.PageCursor
+	}{
+		{name: "empty page", cursor: forward, hasMore: true, n: 0},
+		{name: "only page", n: 3},
+		{name: "first page", hasMore: true, n: 3, wantNext: &utils.PageCursor{Sort: "title", Value: "Casablanca", ID: ids[2]}},
+		{
+			name: "middle page", cursor: forward, hasMore: true, n: 3,
+			wantNext: &utils.PageCursor{Sort: "title", Value: "Casablanca", ID: ids[2]},
+			wantPrev: &utils.PageCursor{Sort: "title", Value: "Alien", ID: ids[0], Before: true},
+		},
+		{
+			name: "last page", cursor: forward, n: 3,
+			wantPrev: &utils.PageCursor{Sort: "title", Value: "Alien", ID: ids[0], Before: true},
+		},
+		{
+			name: "backwards with more before", cursor: backward, hasMore: true, n: 3,
+			wantNext: &utils.PageCursor{Sort: "title", Value: "Casablanca", ID: ids[2]},
+			wantPrev: &utils.PageCursor{Sort: "title", Value: "Alien", ID: ids[0], Before: true},
+		},
+		{
+			name: "backwards to the first page", cursor: backward, n: 3,
+			wantNext: &utils.PageCursor{Sort: "title", Value: "Casablanca", ID: ids[2]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/movies?sort=title&limit=3&cursor=old", nil)
+			next, prev := pageLinks(r, tt.cursor, tt.hasMore, tt.n, cursorAt)
+
+			checkPageLink(t, "next", next, tt.wantNext)
+			checkPageLink(t, "prev", prev, tt.wantPrev)
+		})
+	}
+}
+
+func checkPageLink(t *testing.T, name, link string, want *utils.PageCursor) {
+	t.Helper()
+
+	if want == nil {
+		if link != "" {
+			t.Errorf("%s = %q, want none", name, link)
+		}
+		return
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	query := u.Query()
+	if u.Path != "/movies" || query.Get("sort") != "title" || query.Get("limit") != "3" {
+		t.Errorf("%s = %q does not keep the path and other parameters", name, link)
+	}
+
+	got, err := utils.DecodeCursor(query.Get("cursor"), "title", utils.CursorString)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s cursor = %+v, want %+v", name, got, want)
+	}
+}
+
+func TestMovieFilter(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    bson.M
+		wantErr bool
+	}{
+		{query: "", want: bson.M{}},
+		{query: "genre=28", want: bson.M{"genre.genre_id": 28}},
+		{query: "ranking=2", want: bson.M{"ranking.ranking_value": 2}},
+		{query: "title_prefix=a.b", want: bson.M{"title": bson.M{"$regex": `^a\.b`, "$options": "i"}}},
+		{query: "genre=28&ranking=1", want: bson.M{"genre.genre_id": 28, "ranking.ranking_value": 1}},
+		{query: "genre=action", wantErr: true},
+		{query: "ranking=top", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := movieFilter(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("movieFilter(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("movieFilter(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	movie := modelStructs.Movie{Version: 3}
+
+	tests := []struct {
+		ifMatch    string
+		wantOK     bool
+		wantStatus int
+	}{
+		{ifMatch: `"v3"`, wantOK: true},
+		{ifMatch: `"v2", "v3"`, wantOK: true},
+		{ifMatch: `*`, wantOK: true},
+		{ifMatch: "", wantStatus: http.StatusPreconditionRequired},
+		{ifMatch: `"v2"`, wantStatus: http.StatusPreconditionFailed},
+		{ifMatch: `W/"v3"`, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PATCH", "/movies/tt1", nil)
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		ok := checkIfMatch(w, r, movie)
+		if ok != tt.wantOK {
+			t.Errorf("checkIfMatch(%q) = %v, want %v", tt.ifMatch, ok, tt.wantOK)
+		}
+		if !ok && w.Code != tt.wantStatus {
+			t.Errorf("checkIfMatch(%q) status = %d, want %d", tt.ifMatch, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+// stubMetadata answers every lookup with movie or err.
+type stubMetadata struct {
+	movie *modelStructs.Movie
+	err   error
+}
+
+func (s stubMetadata) LookupMovie(ctx context.Context, imdbId string) (*modelStructs.Movie, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	movie := *s.movie
+	movie.ImdbID = imdbId
+	return &movie, nil
+}
+
+func lookupMovieMetadata(cfg Config, imdbId string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/admin/movies/lookup/"+imdbId, nil)
+	r.SetPathValue("imdb_id", imdbId)
+	w := httptest.NewRecorder()
+	cfg.LookupMovieMetadata(w, r)
+	return w
+}
+
+func TestLookupMovieMetadataDisabled(t *testing.T) {
+	if w := lookupMovieMetadata(Config{}, "tt1"); w.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestLookupMovieMetadata(t *testing.T) {
+	dbName := testDatabase(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := database.OpenCollection("genres", dbName).InsertOne(ctx, modelStructs.Genre{GenreID: 18, GenreName: "Drama"}); err != nil {
+		t.Fatal(err)
+	}
+
+	draft := &modelStructs.Movie{
+		Title: "Fight Club",
+		Genre: []modelStructs.Genre{{GenreID: 18, GenreName: "drama"}, {GenreID: 10759, GenreName: "Action & Adventure"}},
+	}
+
+	tests := []struct {
+		name     string
+		provider utils.MetadataProvider
+		want     int
+	}{
+		{name: "not found", provider: stubMetadata{err: utils.ErrMetadataNotFound}, want: http.StatusNotFound},
+		{name: "provider failure", provider: stubMetadata{err: errors.New("TMDB returned 500")}, want: http.StatusBadGateway},
+		{name: "draft", provider: stubMetadata{movie: draft}, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := lookupMovieMetadata(Config{DbName: dbName, Metadata: tt.provider}, "tt0137523")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var got modelStructs.MovieDraft
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			wantGenres := []modelStructs.Genre{{GenreID: 18, GenreName: "Drama"}}
+			wantUnknown := []modelStructs.Genre{{GenreID: 10759, GenreName: "Action & Adventure"}}
+			if !reflect.DeepEqual(got.Movie.Genre, wantGenres) || !reflect.DeepEqual(got.UnknownGenres, wantUnknown) {
+				t.Errorf("genres = %v, unknown = %v, want %v, %v", got.Movie.Genre, got.UnknownGenres, wantGenres, wantUnknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// OIDCLogin redirects the browser to the provider's authorization endpoint.
+func (cfg Config) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	provider, ok := cfg.OIDCProviders[r.PathValue("provider")]
+	if !ok {
+		http.Error(w, "Unknown login provider", http.StatusNotFound)
+		return
+	}
+
+	deviceName := r.URL.Query().Get("device_name")
+	if err := validate.Var(deviceName, "max=100"); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: device_name: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	state, err := utils.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := utils.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating nonce: %v", err), http.StatusInternalServerError)
+		return
+	}
+	verifier, err := utils.MakePKCEVerifier()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating code verifier: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error contacting login provider: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	pending := modelStructs.OIDCState{
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		DeviceName:   deviceName,
+	}
+	if err := utils.CreateOIDCState(pending, state, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error storing login state: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	utils.SetOIDCStateCookie(w, cfg.Cookies, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes the authorization code flow, links the external
+// identity to a user and sends the browser back to the app logged in.
+func (cfg Config) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	provider, ok := cfg.OIDCProviders[r.PathValue("provider")]
+	if !ok {
+		http.Error(w, "Unknown login provider", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		http.Error(w, fmt.Sprintf("Login provider returned an error: %s", errCode), http.StatusBadRequest)
+		return
+	}
+
+	state := query.Get("state")
+	if err := utils.CheckOIDCStateCookie(r, state); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.ClearOIDCStateCookie(w, cfg.Cookies)
+
+	pending, err := utils.ConsumeOIDCState(state, provider.Name, cfg.DbName)
+	if err != nil {
+		if err == utils.ErrInvalidOIDCState {
+			http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error checking login state: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := provider.Exchange(ctx, query.Get("code"), pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Login provider rejected the request: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.findOrLinkOIDCUser(ctx, provider.Name, claims)
+	if err != nil {
+		if err == errUnverifiedOIDCEmail {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error linking account: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	cfg.redirectOIDCLogin(w, r, *user, pending.DeviceName)
+}
+
+// redirectOIDCLogin is the browser-facing counterpart of finishLogin. In
+// cookie mode the tokens travel in the auth cookies; otherwise they are put
+// in the URL fragment, which the browser never sends to a server. Accounts
+// with two-factor authentication get a challenge token to finish at
+// /login/2fa instead.
+func (cfg Config) redirectOIDCLogin(w http.ResponseWriter, r *http.Request, user modelStructs.User, deviceName string) {
+	if user.Disabled {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	if user.TOTPEnabled {
+		challenge, ok := cfg.createLoginChallenge(w, user, deviceName)
+		if !ok {
+			return
+		}
+
+		fragment := url.Values{"mfa_required": {"true"}, "challenge_token": {challenge}}
+		http.Redirect(w, r, cfg.AppBaseURL+"/login/2fa#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	userRes, err := cfg.startSession(r, user, deviceName, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if cfg.Cookies.Enabled {
+		if err := utils.SetAuthCookies(w, cfg.Cookies, userRes.Token, userRes.RefreshToken); err != nil {
+			http.Error(w, fmt.Sprintf("Error setting cookies: %v", err), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, cfg.AppBaseURL+"/", http.StatusFound)
+		return
+	}
+
+	fragment := url.Values{
+		"token":         {userRes.Token},
+		"refresh_token": {userRes.RefreshToken},
+		"session_id":    {userRes.SessionID},
+	}
+	http.Redirect(w, r, cfg.AppBaseURL+"/#"+fragment.Encode(), http.StatusFound)
+}
+
+var errUnverifiedOIDCEmail = errors.New("login provider did not supply a verified email address")
+
+// findOrLinkOIDCUser resolves the user for an external identity. Known
+// identities map straight to their user; otherwise the identity is linked to
+// the account with the same, provider-verified email, or a new account is
+// created for it. Only accounts whose email was already verified keep their
+// credentials when linked.
+func (cfg Config) findOrLinkOIDCUser(ctx context.Context, provider string, claims *utils.IDTokenClaims) (*modelStructs.User, error) {
+	collection := database.OpenCollection("users", cfg.DbName)
+
+	var user modelStructs.User
+
+	identity := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": claims.Subject}}}
+	err := collection.FindOne(ctx, identity).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedOIDCEmail
+	}
+
+	now := time.Now().UTC()
+	link := modelStructs.ExternalIdentity{Provider: provider, Subject: claims.Subject, LinkedAt: now}
+
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"email": claims.Email, "email_verified": true},
+		bson.M{
+			"$push": bson.M{"identities": link},
+			"$set":  bson.M{"updated_at": now},
+		},
+	).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// An unverified account with this email may have been registered by
+	// someone who does not own the address. The provider has proven that
+	// the caller does, so the account is linked only after its password and
+	// second factor are wiped and every existing session is signed out.
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"email": claims.Email, "email_verified": false},
+		bson.M{
+			"$push": bson.M{"identities": link},
+			"$set": bson.M{
+				"password":          "",
+				"email_verified":    true,
+				"email_verified_at": now,
+				"totp_enabled":      false,
+				"totp_last_step":    0,
+				"updated_at":        now,
+			},
+			"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "recovery_codes": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == nil {
+		if err := cfg.signOutEverywhere(user.UserID); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+
+	user = modelStructs.User{
+		UserID:          bson.NewObjectID().Hex(),
+		FirstName:       firstName,
+		LastName:        lastName,
+		Email:           claims.Email,
+		Role:            "USER",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		FavoriteGenres:  []modelStructs.Genre{},
+		Identities:      []modelStructs.ExternalIdentity{link},
+	}
+
+	if _, err := collection.InsertOne(ctx, user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
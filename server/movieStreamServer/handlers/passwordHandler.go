@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ForgotPassword mails a reset link if the email belongs to an active
+// account. It answers the same way either way so it cannot be used to probe
+// which emails are registered.
+func (cfg Config) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var req modelStructs.ForgotPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Throttled before the lookup so that registered and unknown emails are
+	// limited alike.
+	wait, err := utils.ReservePasswordReset(req.Email, utils.ClientIP(r), cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking reset requests: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many reset requests, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+	err = collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		http.Error(w, fmt.Sprintf("Error finding user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// The token is created and mailed in the background so that the response
+	// takes as long for unknown and disabled accounts as for active ones.
+	if err == nil && !user.Disabled {
+		go cfg.sendPasswordReset(user)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct {
+		Message string `json:"message"`
+	}{
+		Message: "If an account exists for that email, a reset link has been sent",
+	})
+}
+
+// sendPasswordReset creates a reset token for user and mails the link. It
+// runs after the request has been answered, so failures are only logged.
+func (cfg Config) sendPasswordReset(user modelStructs.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := utils.CreatePasswordReset(user.UserID, cfg.DbName)
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return
+	}
+
+	link := cfg.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := utils.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %v.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.FirstName, utils.PasswordResetExpiry, link),
+	}
+	if err := cfg.Mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
+func (cfg Config) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req modelStructs.ResetPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	userId, err := utils.ConsumePasswordReset(req.Token, cfg.DbName)
+	if err != nil {
+		if err == utils.ErrInvalidResetToken {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error checking reset token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// The account may have been disabled after the link was sent.
+	account, err := cfg.findUser(r.Context(), userId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error finding user: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if account.Disabled {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error hashing password: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := cfg.updateUser(r.Context(), userId, bson.M{"password": hashedPassword})
+	if err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	if err := cfg.signOutEverywhere(userId); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.ClearLoginFailures(user.Email, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword sets a new password for the caller after checking the
+// current one, and signs out every other session.
+func (cfg Config) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req modelStructs.ChangePasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	claims := r.Context().Value(utils.ClaimsKey).(*utils.AccessTokenClaims)
+
+	user, err := cfg.findUser(r.Context(), claims.Subject)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Share the login throttle so a stolen access token cannot be used to
+	// brute-force the current password.
+	ip := utils.ClientIP(r)
+	wait, err := utils.ReserveLoginAttempt(user.Email, ip, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := utils.CheckPasswordAndHash(req.CurrentPassword, user.Password); err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ReleaseLoginAttempt(user.Email, ip, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error hashing password: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := cfg.updateUser(r.Context(), user.UserID, bson.M{"password": hashedPassword}); err != nil {
+		writeUpdateUserError(w, err)
+		return
+	}
+
+	if err := utils.DeleteUserSessions(user.UserID, claims.SessionID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error signing out sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// signOutEverywhere revokes every outstanding token of the user and ends all
+// of their sessions.
+func (cfg Config) signOutEverywhere(userId string) error {
+	if err := utils.RevokeUserTokens(userId, cfg.DbName); err != nil {
+		return fmt.Errorf("Error revoking tokens: %v", err)
+	}
+	if err := utils.DeleteUserSessions(userId, "", cfg.DbName); err != nil {
+		return fmt.Errorf("Error signing out sessions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (cfg Config) ListRankings(w http.ResponseWriter, r *http.Request) {
+	rankings, err := utils.GetRankings(cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching rankings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeCachedJSON(w, r, rankings, catalogCacheControl, "", time.Time{})
+}
+
+func (cfg Config) CreateRanking(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var level modelStructs.RankingLevel
+
+	if err := json.NewDecoder(r.Body).Decode(&level); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(level); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	collection := database.OpenCollection("rankings", cfg.DbName)
+	if _, err := collection.InsertOne(ctx, level); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "A ranking with this value or name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error adding ranking: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(level)
+}
+
+func (cfg Config) UpdateRanking(w http.ResponseWriter, r *http.Request) {
+	rankingValue, err := strconv.Atoi(r.PathValue("ranking_value"))
+	if err != nil {
+		http.Error(w, "Invalid ranking value", http.StatusBadRequest)
+		return
+	}
+
+	var req modelStructs.RankingLevelUpdate
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.RankingName == nil && req.Order == nil && req.Hidden == nil {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	level, err := utils.UpdateRanking(rankingValue, req, cfg.DbName)
+	if err != nil {
+		writeRankingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(level)
+}
+
+func (cfg Config) DeleteRanking(w http.ResponseWriter, r *http.Request) {
+	rankingValue, err := strconv.Atoi(r.PathValue("ranking_value"))
+	if err != nil {
+		http.Error(w, "Invalid ranking value", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.DeleteRanking(rankingValue, cfg.DbName); err != nil {
+		writeRankingError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRankingError(w http.ResponseWriter, err error) {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		http.Error(w, "Ranking not found", http.StatusNotFound)
+	case errors.Is(err, utils.ErrRankingInUse), mongo.IsDuplicateKeyError(err):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Error updating ranking: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// searchSort is the only order search results come in: best match first.
+const searchSort = "-score"
+
+// SearchMovies runs a full-text search over titles, admin reviews and genre
+// names. It takes the same filters and cursor parameters as GetMovieHandler.
+func (cfg Config) SearchMovies(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+
+	q := query.Get("q")
+	if q == "" {
+		http.Error(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+
+	var cursor *utils.PageCursor
+	if c := query.Get("cursor"); c != "" {
+		var err error
+		if cursor, err = utils.DecodeCursor(c, searchSort, utils.CursorNumber); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter, err := movieFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter["$text"] = bson.M{"$search": q}
+
+	collection := database.OpenCollection("movies", cfg.DbName)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error counting results: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	before := false
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+	}
+	if cursor != nil {
+		before = cursor.Before
+		pipeline = append(pipeline, primitive.D{{Key: "$match", Value: utils.KeysetFilter("score", true, *cursor)}})
+	}
+
+	limit := utils.PageSize(r)
+	pipeline = append(pipeline,
+		primitive.D{{Key: "$sort", Value: utils.KeysetSort("score", true, before)}},
+		primitive.D{{Key: "$limit", Value: limit + 1}},
+	)
+
+	results := make([]modelStructs.SearchResult, 0)
+
+	cursorRes, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error searching movies: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer cursorRes.Close(ctx)
+
+	if err = cursorRes.All(ctx, &results); err != nil {
+		http.Error(w, fmt.Sprintf("Error Cursor:%s", err), http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := int64(len(results)) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	if before {
+		slices.Reverse(results)
+	}
+
+	terms := utils.SearchTerms(q)
+	for i := range results {
+		results[i].Highlights = highlightMovie(results[i].Movie, terms)
+	}
+
+	page := modelStructs.SearchPage{Results: results, Total: total}
+	page.Next, page.Prev = pageLinks(r, cursor, hasMore, len(results), func(i int, before bool) utils.PageCursor {
+		return utils.PageCursor{Sort: searchSort, Value: results[i].Score, ID: results[i].ID.Hex(), Before: before}
+	})
+
+	writeCachedJSON(w, r, page, catalogCacheControl, "", time.Time{})
+}
+
+func highlightMovie(movie modelStructs.Movie, terms []string) []modelStructs.Highlight {
+	var highlights []modelStructs.Highlight
+
+	add := func(field, text string) {
+		if fragments := utils.Highlight(text, terms); fragments != nil {
+			highlights = append(highlights, modelStructs.Highlight{Field: field, Fragments: fragments})
+		}
+	}
+
+	add("title", movie.Title)
+	add("admin_review", movie.AdminReview)
+	for _, genre := range movie.Genre {
+		add("genre", genre.GenreName)
+	}
+	return highlights
+}
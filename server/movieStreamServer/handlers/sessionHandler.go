@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+func (cfg Config) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(utils.ClaimsKey).(*utils.AccessTokenClaims)
+
+	sessions, err := utils.GetUserSessions(claims.Subject, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == claims.SessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (cfg Config) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.UserIDKey).(string)
+	sessionId := r.PathValue("id")
+
+	found, err := utils.DeleteSession(sessionId, userId, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error signing out session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
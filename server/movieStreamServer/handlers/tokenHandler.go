@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (cfg Config) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var req modelStructs.RefreshRequest
+
+	// Cookie clients send no body; their refresh token comes from the cookie.
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.RefreshToken == "" && cfg.Cookies.Enabled {
+		if err := utils.CheckCSRF(r); err != nil {
+			http.Error(w, fmt.Sprintf("Forbidden: %v", err), http.StatusForbidden)
+			return
+		}
+		req.RefreshToken = utils.GetCookieToken(r, utils.RefreshTokenCookie)
+	}
+
+	if err := validate.Struct(req); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	token, err := utils.RotateRefreshToken(req.RefreshToken, cfg.DbName)
+	if err != nil {
+		switch err {
+		case utils.ErrRefreshTokenReused:
+			http.Error(w, "Refresh token reuse detected, session revoked", http.StatusUnauthorized)
+		case utils.ErrInvalidRefreshToken:
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		default:
+			http.Error(w, fmt.Sprintf("Error rotating refresh token: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	session, err := utils.GetSession(token.FamilyID, cfg.DbName)
+	if err != nil {
+		http.Error(w, "Session has been signed out", http.StatusUnauthorized)
+		return
+	}
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+
+	if err := collection.FindOne(ctx, bson.M{"user_id": token.UserID}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	if user.Disabled {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	userRes, err := cfg.issueTokens(user, *session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.TouchSession(token.FamilyID, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error updating session: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	cfg.writeUserResponse(w, http.StatusOK, userRes)
+}
+
+func (cfg Config) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(utils.ClaimsKey).(*utils.AccessTokenClaims)
+
+	if claims.Role == utils.ServiceRole {
+		http.Error(w, "API keys cannot log out, revoke the key instead", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.RevokeAccessToken(claims, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error revoking token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if claims.SessionID != "" {
+		if _, err := utils.DeleteSession(claims.SessionID, claims.Subject, cfg.DbName); err != nil {
+			http.Error(w, fmt.Sprintf("Error ending session: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if cfg.Cookies.Enabled {
+		utils.ClearAuthCookies(w, cfg.Cookies)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,23 +4,38 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/firebase/genkit/go/genkit"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type Config struct {
-	JwtSecret  string
+	Keys       *utils.Keyring
 	DbName     string
 	BasePrompt string
 	ApiKey     string
 	Genkit     *genkit.Genkit
 	MovieLimit int64
+	// OIDCProviders are the configured social login providers by name.
+	OIDCProviders map[string]*utils.OIDCProvider
+	// RequireAdmin2FA stops admins from switching two-factor authentication
+	// off; middlewares.Config enforces the matching access policy.
+	RequireAdmin2FA bool
+	Mailer          utils.Mailer
+	AppBaseURL      string
+	ApiBaseURL      string
+	Cookies         utils.CookieConfig
+	// Metadata pre-fills new movies by IMDb ID; nil disables lookups.
+	Metadata utils.MetadataProvider
 }
 
 func (cfg Config) AddUser(w http.ResponseWriter, r *http.Request) {
@@ -34,11 +49,25 @@ func (cfg Config) AddUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Roles are only ever granted by admins, never self-assigned.
+	user.Role = "USER"
+	user.Disabled = false
+	user.EmailVerified = false
+	user.EmailVerifiedAt = nil
+	user.TOTPEnabled = false
+
 	if err := validate.Struct(user); err != nil {
 		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
 		return
 	}
 
+	favoriteGenres, err := utils.ResolveGenres(user.FavoriteGenres, cfg.DbName)
+	if err != nil {
+		writeResolveGenresError(w, err)
+		return
+	}
+	user.FavoriteGenres = favoriteGenres
+
 	hashedPassword, err := utils.HashPassword(user.Password)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error hashing password: %v", err), http.StatusInternalServerError)
@@ -67,6 +96,10 @@ func (cfg Config) AddUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := cfg.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(res)
 }
@@ -78,7 +111,25 @@ func (cfg Config) LoginUser(w http.ResponseWriter, r *http.Request) {
 	var userLogin modelStructs.UserLogin
 
 	if err := json.NewDecoder(r.Body).Decode(&userLogin); err != nil {
-		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("error decoding body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(userLogin); err != nil {
+		http.Error(w, fmt.Sprintf("Error validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ip := utils.ClientIP(r)
+
+	wait, err := utils.ReserveLoginAttempt(userLogin.Email, ip, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many login attempts, try again later", http.StatusTooManyRequests)
 		return
 	}
 
@@ -86,34 +137,159 @@ func (cfg Config) LoginUser(w http.ResponseWriter, r *http.Request) {
 
 	collection := database.OpenCollection("users", cfg.DbName)
 
-	if err := collection.FindOne(ctx, bson.M{"email": userLogin.Email}).Decode(&user); err != nil {
-		http.Error(w, "Invalid Email", http.StatusUnauthorized)
+	err = collection.FindOne(ctx, bson.M{"email": userLogin.Email}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		http.Error(w, fmt.Sprintf("Error finding user: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		utils.CheckDummyPassword(userLogin.Password)
+	} else {
+		err = utils.CheckPasswordAndHash(userLogin.Password, user.Password)
+	}
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ReleaseLoginAttempt(userLogin.Email, ip, cfg.DbName); err != nil {
+		http.Error(w, fmt.Sprintf("Error clearing login attempts: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	if err := utils.CheckPasswordAndHash(userLogin.Password, user.Password); err != nil {
-		http.Error(w, "Wrong Password", http.StatusUnauthorized)
+	if utils.PasswordNeedsRehash(user.Password) {
+		if hash, err := utils.HashPassword(userLogin.Password); err != nil {
+			log.Printf("Failed to rehash password: %v", err)
+		} else if _, err := cfg.updateUser(ctx, user.UserID, bson.M{"password": hash}); err != nil {
+			log.Printf("Failed to store rehashed password: %v", err)
+		}
+	}
+
+	cfg.finishLogin(w, r, user, userLogin.DeviceName)
+}
+
+// finishLogin completes a login whose first factor has been verified: it
+// either issues tokens or, for accounts with two-factor authentication,
+// answers with a challenge for the second factor.
+func (cfg Config) finishLogin(w http.ResponseWriter, r *http.Request, user modelStructs.User, deviceName string) {
+	if user.Disabled {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
+
+	if user.TOTPEnabled {
+		challenge, ok := cfg.createLoginChallenge(w, user, deviceName)
+		if !ok {
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(modelStructs.LoginChallengeResponse{
+			MFARequired:    true,
+			ChallengeToken: challenge,
+		})
 		return
 	}
 
-	hashedPass, err := utils.MakeJwt(user.UserID, cfg.JwtSecret, user.Role, time.Hour)
+	userRes, err := cfg.startSession(r, user, deviceName, false)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error creating JWT: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	cfg.writeUserResponse(w, http.StatusCreated, userRes)
+}
+
+// createLoginChallenge issues a second-factor challenge for user unless too
+// many codes have recently failed for the account, in which case it answers
+// 429 and reports false.
+func (cfg Config) createLoginChallenge(w http.ResponseWriter, user modelStructs.User, deviceName string) (string, bool) {
+	wait, err := utils.TwoFactorRetryAfter(user.UserID, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error checking login attempts: %v", err), http.StatusInternalServerError)
+		return "", false
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many login attempts, try again later", http.StatusTooManyRequests)
+		return "", false
+	}
+
+	challenge, err := utils.CreateLoginChallenge(user.UserID, deviceName, cfg.DbName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating login challenge: %v", err), http.StatusInternalServerError)
+		return "", false
+	}
+	return challenge, true
+}
+
+// writeUserResponse sends freshly issued tokens to the client. In cookie mode
+// they go into HttpOnly cookies and are left out of the body.
+func (cfg Config) writeUserResponse(w http.ResponseWriter, status int, userRes modelStructs.UserResponse) {
+	if cfg.Cookies.Enabled {
+		if err := utils.SetAuthCookies(w, cfg.Cookies, userRes.Token, userRes.RefreshToken); err != nil {
+			http.Error(w, fmt.Sprintf("Error setting cookies: %v", err), http.StatusInternalServerError)
+			return
+		}
+		userRes.Token = ""
+		userRes.RefreshToken = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(userRes)
+}
+
+// startSession records a new device session for user and issues its first
+// pair of tokens. mfa marks sessions that passed a second factor.
+func (cfg Config) startSession(r *http.Request, user modelStructs.User, deviceName string, mfa bool) (modelStructs.UserResponse, error) {
+	now := time.Now().UTC()
+	session := modelStructs.Session{
+		SessionID:  bson.NewObjectID().Hex(),
+		UserID:     user.UserID,
+		DeviceName: deviceName,
+		UserAgent:  r.UserAgent(),
+		IP:         utils.ClientIP(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenExpiry),
+		MFA:        mfa,
+	}
+
+	if err := utils.CreateSession(session, cfg.DbName); err != nil {
+		return modelStructs.UserResponse{}, fmt.Errorf("Error creating session: %v", err)
+	}
+
+	return cfg.issueTokens(user, session)
+}
+
+// issueTokens creates a fresh access/refresh pair for user within a session.
+// The session ID doubles as the refresh token family, so reuse of any rotated
+// token revokes the whole session.
+func (cfg Config) issueTokens(user modelStructs.User, session modelStructs.Session) (modelStructs.UserResponse, error) {
+	claims := utils.AccessTokenClaims{
+		Role:          user.Role,
+		SessionID:     session.SessionID,
+		EmailVerified: user.EmailVerified,
+		MFA:           session.MFA,
+	}
+	claims.Subject = user.UserID
+
+	hashedPass, err := utils.MakeJwt(claims, cfg.Keys, utils.AccessTokenExpiry)
+	if err != nil {
+		return modelStructs.UserResponse{}, fmt.Errorf("Error creating JWT: %v", err)
+	}
+
 	refreshToken, err := utils.MakeRefreshToken()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error creating Refresh Token: %v", err), http.StatusInternalServerError)
-		return
+		return modelStructs.UserResponse{}, fmt.Errorf("Error creating Refresh Token: %v", err)
 	}
 
-	if err := utils.AddToken(user.UserID, hashedPass, refreshToken, cfg.DbName); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update token: %v", err), http.StatusInternalServerError)
-		return
+	if err := utils.StoreRefreshToken(user.UserID, session.SessionID, refreshToken, cfg.DbName); err != nil {
+		return modelStructs.UserResponse{}, fmt.Errorf("Failed to store refresh token: %v", err)
 	}
 
-	userRes := modelStructs.UserResponse{
+	return modelStructs.UserResponse{
 		UserID:         user.UserID,
 		FirstName:      user.FirstName,
 		LastName:       user.LastName,
@@ -121,9 +297,8 @@ func (cfg Config) LoginUser(w http.ResponseWriter, r *http.Request) {
 		Role:           user.Role,
 		Token:          hashedPass,
 		RefreshToken:   refreshToken,
+		SessionID:      session.SessionID,
+		EmailVerified:  user.EmailVerified,
 		FavoriteGenres: user.FavoriteGenres,
-	}
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(userRes)
-
+	}, nil
 }
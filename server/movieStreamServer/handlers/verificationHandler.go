@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (cfg Config) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims, err := utils.ValidateEmailVerificationToken(r.URL.Query().Get("token"), cfg.Keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	collection := database.OpenCollection("users", cfg.DbName)
+
+	// Matching on the email as well rejects links sent to a previous address.
+	filter := bson.M{"user_id": claims.Subject, "email": claims.Email}
+	update := bson.M{"$set": bson.M{
+		"email_verified":    true,
+		"email_verified_at": now,
+		"updated_at":        now,
+	}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error verifying email: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message string `json:"message"`
+	}{
+		Message: "Email verified, refresh your session to continue",
+	})
+}
+
+func (cfg Config) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userId := r.Context().Value(utils.UserIDKey).(string)
+
+	var user modelStructs.User
+
+	collection := database.OpenCollection("users", cfg.DbName)
+	if err := collection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if user.EmailVerified {
+		http.Error(w, "Email already verified", http.StatusConflict)
+		return
+	}
+
+	if err := cfg.sendVerificationEmail(ctx, user); err != nil {
+		http.Error(w, fmt.Sprintf("Error sending verification email: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg Config) sendVerificationEmail(ctx context.Context, user modelStructs.User) error {
+	token, err := utils.MakeEmailVerificationToken(user.UserID, user.Email, cfg.Keys)
+	if err != nil {
+		return err
+	}
+
+	link := cfg.ApiBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	return cfg.Mailer.Send(ctx, utils.MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below. It expires in %v.\n\n%s\n",
+			user.FirstName, utils.EmailVerificationExpiry, link),
+	})
+}
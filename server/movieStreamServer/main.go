@@ -9,10 +9,12 @@ import (
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/handlers"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/middlewares"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -31,26 +33,169 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	signingAlg := os.Getenv("JWT_SIGNING_ALG")
+	if signingAlg == "" {
+		signingAlg = utils.AlgHS256
+	}
+	appBaseURL := os.Getenv("APP_BASE_URL")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:5173"
+	}
+	apiBaseURL := os.Getenv("API_BASE_URL")
+	if apiBaseURL == "" {
+		apiBaseURL = "http://localhost:8080"
+	}
 
-	g := genkit.Init(context.Background(), genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: apiKeyGemini}),
-		genkit.WithDefaultModel("googleai/gemini-2.5-flash"))
+	var mailer utils.Mailer = &utils.LogMailer{Path: os.Getenv("MAIL_LOG_FILE")}
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		mailer = utils.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	}
 
-	authCfg := middlewares.Config{
-		JwtSecret: secret,
+	requireAdmin2FA := os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+
+	var metadata utils.MetadataProvider
+	switch os.Getenv("METADATA_PROVIDER") {
+	case "":
+	case "tmdb":
+		metadata = utils.TMDBProvider{
+			Token:        os.Getenv("TMDB_API_TOKEN"),
+			BaseURL:      os.Getenv("TMDB_BASE_URL"),
+			ImageBaseURL: os.Getenv("TMDB_IMAGE_BASE_URL"),
+		}
+	case "fixture":
+		metadata = utils.FixtureProvider{Path: os.Getenv("METADATA_FIXTURE_FILE")}
+	default:
+		log.Fatalf("Unknown METADATA_PROVIDER: %s", os.Getenv("METADATA_PROVIDER"))
 	}
-	handlerCfg := handlers.Config{
-		JwtSecret:  secret,
-		DbName:     dbName,
-		BasePrompt: basePrompt,
-		ApiKey:     apiKeyGroq,
-		Genkit:     g,
-		MovieLimit: movieLimit,
+
+	// AUTH_COOKIES switches browser clients to HttpOnly cookies with
+	// double-submit CSRF protection. Bearer tokens keep working either way.
+	cookies := utils.CookieConfig{
+		Enabled:  os.Getenv("AUTH_COOKIES") == "true",
+		Secure:   os.Getenv("COOKIE_SECURE") != "false",
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		SameSite: http.SameSiteLaxMode,
+	}
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "", "lax":
+	case "strict":
+		cookies.SameSite = http.SameSiteStrictMode
+	case "none":
+		cookies.SameSite = http.SameSiteNoneMode
+	default:
+		log.Fatalf("Invalid COOKIE_SAMESITE: %s", os.Getenv("COOKIE_SAMESITE"))
+	}
+
+	passwordParams := utils.DefaultPasswordParams()
+	for name, field := range map[string]*uint32{
+		"ARGON2_MEMORY":      &passwordParams.Memory,
+		"ARGON2_ITERATIONS":  &passwordParams.Iterations,
+		"ARGON2_SALT_LENGTH": &passwordParams.SaltLength,
+		"ARGON2_KEY_LENGTH":  &passwordParams.KeyLength,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				log.Fatalf("Invalid %s: %v", name, err)
+			}
+			*field = uint32(n)
+		}
+	}
+	if v := os.Getenv("ARGON2_PARALLELISM"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			log.Fatalf("Invalid ARGON2_PARALLELISM: %v", err)
+		}
+		passwordParams.Parallelism = uint8(n)
+	}
+	utils.SetPasswordParams(passwordParams)
+
+	// OIDC_PROVIDERS lists provider names; each is configured through
+	// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES.
+	oidcProviders := map[string]*utils.OIDCProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		scopes := strings.Fields(os.Getenv(prefix + "SCOPES"))
+		if len(scopes) == 0 {
+			scopes = []string{"openid", "email", "profile"}
+		}
+		oidcProviders[name] = &utils.OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       scopes,
+			HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	keyRotation := 30 * 24 * time.Hour
+	if v := os.Getenv("JWT_KEY_ROTATION"); v != "" {
+		keyRotation, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid JWT_KEY_ROTATION: %v", err)
+		}
 	}
 
+	g := genkit.Init(context.Background(), genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: apiKeyGemini}),
+		genkit.WithDefaultModel("googleai/gemini-2.5-flash"))
+
 	if err = database.DBinstance(uri); err != nil {
 		log.Fatalf("Mongo connection failed: %v", err)
 	}
 
+	// Migrations run first since some of them clean up data that would
+	// otherwise stop a unique index from being built.
+	if err = database.Migrate(dbName); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	if err = database.EnsureIndexes(dbName); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+
+	keys := utils.NewHMACKeyring(secret)
+	if signingAlg != utils.AlgHS256 {
+		keys, err = utils.LoadKeyring(signingAlg, dbName, keyRotation)
+		if err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		keys.StartRotation(context.Background(), time.Minute)
+	}
+
+	authCfg := middlewares.Config{
+		Keys:            keys,
+		DbName:          dbName,
+		RequireAdmin2FA: requireAdmin2FA,
+		Cookies:         cookies,
+	}
+	handlerCfg := handlers.Config{
+		Keys:            keys,
+		DbName:          dbName,
+		BasePrompt:      basePrompt,
+		ApiKey:          apiKeyGroq,
+		Genkit:          g,
+		MovieLimit:      movieLimit,
+		RequireAdmin2FA: requireAdmin2FA,
+		OIDCProviders:   oidcProviders,
+		Mailer:          mailer,
+		AppBaseURL:      appBaseURL,
+		ApiBaseURL:      apiBaseURL,
+		Cookies:         cookies,
+		Metadata:        metadata,
+	}
+
 	defer func() {
 		err := database.Client.Disconnect(context.Background())
 		if err != nil {
@@ -68,12 +213,52 @@ func main() {
 	}
 
 	mux.Handle("GET /movie/{imdb_id}", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.GetOneMovieHandler)))
-	mux.Handle("POST /addmovie", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.AddMovie)))
+	mux.Handle("POST /addmovie", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, authCfg.Idempotent(http.HandlerFunc(handlerCfg.AddMovie)))))
+	mux.Handle("PATCH /movie/{imdb_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.UpdateMovie))))
+	mux.Handle("DELETE /movie/{imdb_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.DeleteMovie))))
+	mux.Handle("POST /admin/movies/import", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.ImportMovies))))
+	mux.Handle("GET /export/movies", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.ExportMovies))))
+	mux.Handle("GET /admin/movies/lookup/{imdb_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.LookupMovieMetadata))))
+	mux.Handle("POST /admin/genres", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.CreateGenre))))
+	mux.Handle("PATCH /admin/genres/{genre_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.UpdateGenre))))
+	mux.Handle("DELETE /admin/genres/{genre_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.DeleteGenre))))
+	mux.Handle("POST /admin/rankings", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.CreateRanking))))
+	mux.Handle("PATCH /admin/rankings/{ranking_value}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.UpdateRanking))))
+	mux.Handle("DELETE /admin/rankings/{ranking_value}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermMovieWrite, http.HandlerFunc(handlerCfg.DeleteRanking))))
 	mux.Handle("GET /recmovies", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.GetRecommendations)))
-	mux.Handle("PATCH /adminreview/{imdb_id}", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.AdminReview)))
+	mux.Handle("PATCH /adminreview/{imdb_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermReviewRank, http.HandlerFunc(handlerCfg.AdminReview))))
+	mux.Handle("POST /logout", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.Logout)))
+	mux.Handle("GET /sessions", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.GetSessions)))
+	mux.Handle("DELETE /sessions/{id}", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.DeleteSession)))
+	mux.Handle("GET /admin/users", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.ListUsers))))
+	mux.Handle("PATCH /admin/users/{user_id}/role", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.UpdateUserRole))))
+	mux.Handle("POST /admin/users/{user_id}/disable", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.DisableUser))))
+	mux.Handle("POST /admin/users/{user_id}/enable", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.EnableUser))))
+	mux.Handle("POST /admin/users/{user_id}/reset-credentials", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.ResetUserCredentials))))
+	mux.Handle("POST /admin/users/{user_id}/unlock", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.UnlockUser))))
+	mux.Handle("POST /admin/api-keys", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.CreateAPIKey))))
+	mux.Handle("GET /admin/api-keys", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.ListAPIKeys))))
+	mux.Handle("DELETE /admin/api-keys/{key_id}", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.RevokeAPIKey))))
+	mux.Handle("GET /admin/audit-log", authCfg.AuthMiddleware(authCfg.RequirePermission(utils.PermUserAdmin, http.HandlerFunc(handlerCfg.GetAuditLog))))
 	mux.HandleFunc("GET /movies", handlerCfg.GetMovieHandler)
+	mux.HandleFunc("GET /genres", handlerCfg.ListGenres)
+	mux.HandleFunc("GET /rankings", handlerCfg.ListRankings)
+	mux.HandleFunc("GET /search", handlerCfg.SearchMovies)
+	mux.HandleFunc("GET /.well-known/jwks.json", handlerCfg.GetJWKS)
 	mux.HandleFunc("POST /register", handlerCfg.AddUser)
 	mux.HandleFunc("POST /login", handlerCfg.LoginUser)
+	mux.HandleFunc("POST /login/2fa", handlerCfg.LoginTwoFactor)
+	mux.HandleFunc("POST /refresh", handlerCfg.RefreshToken)
+	mux.HandleFunc("GET /auth/{provider}/login", handlerCfg.OIDCLogin)
+	mux.HandleFunc("GET /auth/{provider}/callback", handlerCfg.OIDCCallback)
+	mux.Handle("POST /2fa/enroll", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.EnrollTOTP)))
+	mux.Handle("POST /2fa/confirm", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.ConfirmTOTP)))
+	mux.Handle("POST /2fa/disable", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.DisableTOTP)))
+	mux.HandleFunc("GET /verify-email", handlerCfg.VerifyEmail)
+	mux.Handle("POST /verify-email/resend", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.ResendVerification)))
+	mux.Handle("POST /password/change", authCfg.AuthMiddleware(http.HandlerFunc(handlerCfg.ChangePassword)))
+	mux.HandleFunc("POST /password/forgot", handlerCfg.ForgotPassword)
+	mux.HandleFunc("POST /password/reset", handlerCfg.ResetPassword)
 
 	fmt.Println("Starting movie stream server on :8080")
 	log.Fatal(srv.ListenAndServe())
@@ -8,29 +8,122 @@ import (
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
 )
 
+// unverifiedRoutes are the only routes an account that has not verified its
+// email address may call.
+var unverifiedRoutes = map[string]bool{
+	"GET /movie/{imdb_id}":      true,
+	"GET /sessions":             true,
+	"DELETE /sessions/{id}":     true,
+	"POST /logout":              true,
+	"POST /verify-email/resend": true,
+}
+
 type Config struct {
-	JwtSecret string
+	Keys   *utils.Keyring
+	DbName string
+	// RequireAdmin2FA denies ADMIN tokens that were not obtained with a
+	// second factor, and API keys whose owner has none, any
+	// permission-guarded route.
+	RequireAdmin2FA bool
+	Cookies         utils.CookieConfig
 }
 
 func (cfg *Config) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := utils.GetBearerToken(r.Header)
+		if key := utils.GetAPIKey(r.Header); key != "" {
+			cfg.serveAPIKey(w, r, key, next)
+			return
+		}
+
+		token, err := cfg.getToken(r)
 		if err != nil {
+			if err == utils.ErrCSRFTokenMismatch {
+				http.Error(w, fmt.Sprintf("Forbidden: %v", err), http.StatusForbidden)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		claims, err := utils.ValidateJwt(token, cfg.JwtSecret)
+		claims, err := utils.ValidateJwt(token, cfg.Keys)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
 			return
 		}
 
+		revoked, err := utils.IsTokenRevoked(claims, cfg.DbName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error checking token revocation: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			http.Error(w, "Unauthorized: token has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		if !claims.EmailVerified && !unverifiedRoutes[r.Pattern] {
+			http.Error(w, "Forbidden: email address not verified", http.StatusForbidden)
+			return
+		}
+
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, "userID", claims.RegisteredClaims.Subject)
-		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, utils.UserIDKey, claims.RegisteredClaims.Subject)
+		ctx = context.WithValue(ctx, utils.RoleKey, claims.Role)
+		ctx = context.WithValue(ctx, utils.ClaimsKey, claims)
+		ctx = context.WithValue(ctx, utils.PermissionsKey, utils.PermissionsForRole(claims.Role))
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 
 	})
 }
+
+// getToken prefers the Authorization header. In cookie mode it falls back to
+// the access token cookie, which browsers attach on their own, so those
+// requests must also pass the CSRF check.
+func (cfg *Config) getToken(r *http.Request) (string, error) {
+	token, err := utils.GetBearerToken(r.Header)
+	if err == nil || !cfg.Cookies.Enabled || r.Header.Get("Authorization") != "" {
+		return token, err
+	}
+
+	token = utils.GetCookieToken(r, utils.AccessTokenCookie)
+	if token == "" {
+		return "", err
+	}
+	if err := utils.CheckCSRF(r); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (cfg *Config) serveAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	apiKey, err := utils.AuthenticateAPIKey(key, cfg.DbName)
+	if err != nil {
+		if err == utils.ErrInvalidAPIKey {
+			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error checking API key: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	owner, err := utils.GetAPIKeyOwner(apiKey, cfg.DbName)
+	if err != nil {
+		if err == utils.ErrInvalidAPIKey {
+			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Error checking API key: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	claims := utils.APIKeyClaims(apiKey, owner)
+
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, utils.UserIDKey, claims.Subject)
+	ctx = context.WithValue(ctx, utils.RoleKey, claims.Role)
+	ctx = context.WithValue(ctx, utils.ClaimsKey, claims)
+	ctx = context.WithValue(ctx, utils.PermissionsKey, utils.APIKeyPermissions(apiKey, owner))
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
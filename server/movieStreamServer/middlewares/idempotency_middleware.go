@@ -0,0 +1,108 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotent makes requests carrying an Idempotency-Key header safe to retry:
+// the first request with a key runs normally and its response is stored,
+// later ones with the same key and body get that response replayed. Keys are
+// scoped to the caller, so it must be wrapped by AuthMiddleware.
+func (cfg *Config) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > 255 {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading body: %v", err), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		userId := r.Context().Value(utils.UserIDKey).(string)
+		scoped := utils.HashToken(userId + "\n" + r.Method + " " + r.URL.Path + "\n" + key)
+		requestHash := sha256.Sum256(body)
+
+		existing, err := utils.BeginIdempotentRequest(scoped, hex.EncodeToString(requestHash[:]), cfg.DbName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error checking idempotency key: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != hex.EncodeToString(requestHash[:]):
+				http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+			case !existing.Completed:
+				http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			default:
+				for name, values := range existing.Headers {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.Body)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// Server errors may be transient, so let the client retry them.
+		if rec.status >= http.StatusInternalServerError {
+			if err := utils.AbortIdempotentRequest(scoped, cfg.DbName); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		if err := utils.CompleteIdempotentRequest(scoped, rec.status, rec.Header(), rec.body.Bytes(), cfg.DbName); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	})
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
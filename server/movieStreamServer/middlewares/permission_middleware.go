@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/utils"
+)
+
+// RequirePermission rejects requests whose caller lacks perm. It must be
+// wrapped by AuthMiddleware, which populates the caller's permissions.
+func (cfg *Config) RequirePermission(perm utils.Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !utils.HasPermission(r.Context(), perm) {
+			http.Error(w, fmt.Sprintf("Forbidden: missing permission %s", perm), http.StatusForbidden)
+			return
+		}
+
+		claims := r.Context().Value(utils.ClaimsKey).(*utils.AccessTokenClaims)
+		privileged := claims.Role == "ADMIN" || claims.Role == utils.ServiceRole
+		if cfg.RequireAdmin2FA && privileged && !claims.MFA {
+			http.Error(w, "Forbidden: two-factor authentication required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,31 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	KeyID      string             `bson:"key_id" json:"key_id"`
+	Name       string             `bson:"name" json:"name"`
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedBy  string             `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+// APIKeyCreated is returned once when a key is created; Key is never stored
+// or shown again.
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,16 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditEntry struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ActorID   string                 `bson:"actor_id" json:"actor_id"`
+	Action    string                 `bson:"action" json:"action"`
+	TargetID  string                 `bson:"target_id" json:"target_id"`
+	Details   map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+}
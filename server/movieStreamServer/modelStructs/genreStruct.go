@@ -0,0 +1,5 @@
+package modelStructs
+
+type GenreUpdate struct {
+	GenreName string `json:"genre_name" validate:"required,min=2,max=100"`
+}
@@ -0,0 +1,17 @@
+package modelStructs
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request sent with an
+// Idempotency-Key header so a retry gets the same response instead of
+// repeating the side effects.
+type IdempotencyRecord struct {
+	Key         string              `bson:"key" json:"key"`
+	RequestHash string              `bson:"request_hash" json:"request_hash"`
+	Completed   bool                `bson:"completed" json:"completed"`
+	StatusCode  int                 `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Headers     map[string][]string `bson:"headers,omitempty" json:"headers,omitempty"`
+	Body        []byte              `bson:"body,omitempty" json:"-"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+}
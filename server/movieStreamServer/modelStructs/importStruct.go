@@ -0,0 +1,25 @@
+package modelStructs
+
+const (
+	ImportInserted = "inserted"
+	ImportUpdated  = "updated"
+	ImportRejected = "rejected"
+)
+
+// ImportReport summarises a bulk movie import with one entry per input row.
+type ImportReport struct {
+	Inserted int               `json:"inserted"`
+	Updated  int               `json:"updated"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowResult `json:"rows"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// ImportRowResult is the outcome for one input row. Line is the line number
+// in the uploaded file.
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	ImdbID string `json:"imdb_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
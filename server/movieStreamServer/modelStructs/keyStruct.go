@@ -0,0 +1,35 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKey is a persisted JWT signing key. Active keys have no ExpiresAt;
+// once a newer key takes over, ExpiresAt is set to when the last token the
+// key signed runs out, after which MongoDB removes the record.
+type SigningKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Kid        string             `bson:"kid" json:"kid"`
+	Alg        string             `bson:"alg" json:"alg"`
+	PrivateKey string             `bson:"private_key" json:"-"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,13 @@
+package modelStructs
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for one throttling key,
+// either an email address or a client IP.
+type LoginAttempt struct {
+	Key           string     `bson:"key" json:"key"`
+	Failures      int        `bson:"failures" json:"failures"`
+	LastFailureAt time.Time  `bson:"last_failure_at" json:"last_failure_at"`
+	NextAttemptAt *time.Time `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	ExpiresAt     time.Time  `bson:"expires_at" json:"expires_at"`
+}
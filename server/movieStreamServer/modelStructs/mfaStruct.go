@@ -0,0 +1,49 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginChallenge is created when a password check succeeds for an account
+// with two-factor authentication; the second factor redeems it for tokens.
+type LoginChallenge struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	UserID     string             `bson:"user_id" json:"user_id"`
+	DeviceName string             `bson:"device_name" json:"device_name"`
+	Attempts   int                `bson:"attempts" json:"attempts"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+type LoginChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPConfirm struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TOTPDisable struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,18 +1,25 @@
 package modelStructs
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Movie struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	ImdbID     string             `bson:"imdb_id" json:"imdb_id" validate:"required"`
-	Title      string             `bson:"title" json:"title" validate:"required,min=2,max=500"`
-	PosterPath string             `bson:"poster_path" json:"poster_path" validate:"required,url"`
-	YouTubeID  string             `bson:"youtube_id" json:"youtube_id" validate:"required"`
-	Genre      []Genre            `bson:"genre" json:"genre" validate:"required,dive"`
-	AdminScore string             `bson:"admin_score" json:"admin_score"`
-	Ranking    Ranking            `bson:"ranking" json:"ranking" validate:"required"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ImdbID      string             `bson:"imdb_id" json:"imdb_id" validate:"required"`
+	Title       string             `bson:"title" json:"title" validate:"required,min=2,max=500"`
+	PosterPath  string             `bson:"poster_path" json:"poster_path" validate:"required,url"`
+	YouTubeID   string             `bson:"youtube_id" json:"youtube_id" validate:"required"`
+	Genre       []Genre            `bson:"genre" json:"genre" validate:"required,dive"`
+	AdminScore  string             `bson:"admin_score" json:"admin_score"`
+	AdminReview string             `bson:"admin_review,omitempty" json:"admin_review,omitempty"`
+	Ranking     Ranking            `bson:"ranking" json:"ranking" validate:"required"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	// Version increases with every write and is served as the ETag, so
+	// edits can be made conditional with If-Match.
+	Version int64 `bson:"version" json:"version"`
 }
 
 type Genre struct {
@@ -24,3 +31,49 @@ type Ranking struct {
 	RankingValue int    `bson:"ranking_value" json:"ranking_value" validate:"required"`
 	RankingName  string `bson:"ranking_name" json:"ranking_name" validate:"required"`
 }
+
+// MoviePage is one page of a cursor-paginated movie listing. Next and Prev
+// are links to the neighbouring pages and are omitted at either end.
+type MoviePage struct {
+	Movies []Movie `json:"movies"`
+	Total  int64   `json:"total"`
+	Next   string  `json:"next,omitempty"`
+	Prev   string  `json:"prev,omitempty"`
+}
+
+// SearchResult is a movie matched by a text search together with its
+// relevance score and the matched terms in each searched field.
+type SearchResult struct {
+	Movie      `bson:",inline"`
+	Score      float64     `bson:"score" json:"score"`
+	Highlights []Highlight `bson:"-" json:"highlights,omitempty"`
+}
+
+// Highlight splits one field value into fragments so clients can emphasise
+// the matched ones without parsing markup.
+type Highlight struct {
+	Field     string              `json:"field"`
+	Fragments []HighlightFragment `json:"fragments"`
+}
+
+type HighlightFragment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Total   int64          `json:"total"`
+	Next    string         `json:"next,omitempty"`
+	Prev    string         `json:"prev,omitempty"`
+}
+
+// MovieDraft is a movie pre-filled by a metadata provider. Missing lists the
+// fields that still fail validation and need an admin's input.
+type MovieDraft struct {
+	Movie   Movie    `json:"movie"`
+	Missing []string `json:"missing"`
+	// UnknownGenres are genres the provider listed that the catalog does not
+	// have; they are left out of Movie.
+	UnknownGenres []Genre `json:"unknown_genres"`
+}
@@ -0,0 +1,20 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCState remembers an authorization request between the redirect to the
+// provider and its callback.
+type OIDCState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	StateHash    string             `bson:"state_hash" json:"-"`
+	Provider     string             `bson:"provider" json:"provider"`
+	Nonce        string             `bson:"nonce" json:"-"`
+	CodeVerifier string             `bson:"code_verifier" json:"-"`
+	DeviceName   string             `bson:"device_name" json:"device_name"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`
+}
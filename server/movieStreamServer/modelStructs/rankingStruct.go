@@ -0,0 +1,19 @@
+package modelStructs
+
+// RankingLevel is one step of the ranking scale. Movies embed its value and
+// name as a Ranking. Order sets the display order, and Hidden levels such as
+// "not ranked" are never offered to the review model.
+type RankingLevel struct {
+	RankingValue int    `bson:"ranking_value" json:"ranking_value" validate:"required"`
+	RankingName  string `bson:"ranking_name" json:"ranking_name" validate:"required,min=2,max=100"`
+	Order        int    `bson:"order" json:"order"`
+	Hidden       bool   `bson:"hidden" json:"hidden"`
+}
+
+// RankingLevelUpdate changes the fields that are set; the value itself is
+// the level's identity and cannot change.
+type RankingLevelUpdate struct {
+	RankingName *string `json:"ranking_name" validate:"omitempty,min=2,max=100"`
+	Order       *int    `json:"order"`
+	Hidden      *bool   `json:"hidden"`
+}
@@ -0,0 +1,21 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	SessionID  string             `bson:"session_id" json:"session_id"`
+	UserID     string             `bson:"user_id" json:"user_id"`
+	DeviceName string             `bson:"device_name" json:"device_name"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	MFA        bool               `bson:"mfa" json:"mfa"`
+	Current    bool               `bson:"-" json:"current"`
+}
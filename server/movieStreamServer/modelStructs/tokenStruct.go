@@ -0,0 +1,33 @@
+package modelStructs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	FamilyID  string             `bson:"family_id" json:"family_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	RotatedAt *time.Time         `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RevokedToken blocks a single access token by its jti, every access token
+// of a session, or every access token issued to UserID before RevokedAt.
+// Records expire once the tokens they cover could no longer validate anyway.
+type RevokedToken struct {
+	TokenID   string    `bson:"jti,omitempty" json:"jti,omitempty"`
+	SessionID string    `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	UserID    string    `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	RevokedAt time.Time `bson:"revoked_at" json:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -7,23 +7,40 @@ import (
 )
 
 type User struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID         string             `bson:"user_id" json:"user_id"`
-	FirstName      string             `bson:"first_name" json:"first_name" validate:"required,min=2,max=100"`
-	LastName       string             `bson:"last_name" json:"last_name" validate:"required,min=2,max=100"`
-	Email          string             `bson:"email" json:"email" validate:"required,email"`
-	Password       string             `bson:"password" json:"password" validate:"required,min=8"`
-	Role           string             `bson:"role" json:"role" validate:"required,oneof=ADMIN USER"`
-	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
-	Token          string             `bson:"token" json:"token"`
-	RefreshToken   string             `bson:"refresh_token" json:"refresh_token"`
-	FavoriteGenres []Genre            `bson:"favorite_genres" json:"favorite_genres" validate:"required,dive"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	FirstName       string             `bson:"first_name" json:"first_name" validate:"required,min=2,max=100"`
+	LastName        string             `bson:"last_name" json:"last_name" validate:"required,min=2,max=100"`
+	Email           string             `bson:"email" json:"email" validate:"required,email"`
+	Password        string             `bson:"password" json:"password" validate:"required,min=8"`
+	Role            string             `bson:"role" json:"role" validate:"required,oneof=ADMIN USER"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	Disabled        bool               `bson:"disabled" json:"disabled"`
+	EmailVerified   bool               `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt *time.Time         `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	FavoriteGenres  []Genre            `bson:"favorite_genres" json:"favorite_genres" validate:"required,dive"`
+
+	TOTPEnabled       bool     `bson:"totp_enabled" json:"-"`
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totp_last_step" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
+
+	Identities []ExternalIdentity `bson:"identities,omitempty" json:"-"`
+}
+
+// ExternalIdentity links an account at an OpenID Connect provider to a user.
+type ExternalIdentity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"subject"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
 }
 
 type UserLogin struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=8"`
+	DeviceName string `json:"device_name" validate:"max=100"`
 }
 
 type UserResponse struct {
@@ -32,7 +49,33 @@ type UserResponse struct {
 	LastName       string  `json:"last_name"`
 	Email          string  `json:"email"`
 	Role           string  `json:"role"`
-	Token          string  `json:"token"`
-	RefreshToken   string  `json:"refresh_token"`
+	Token          string  `json:"token,omitempty"`
+	RefreshToken   string  `json:"refresh_token,omitempty"`
+	SessionID      string  `json:"session_id"`
+	EmailVerified  bool    `json:"email_verified"`
 	FavoriteGenres []Genre `json:"favorite_genres"`
 }
+
+// UserSummary is the admin view of an account; it never carries credentials.
+type UserSummary struct {
+	UserID         string    `bson:"user_id" json:"user_id"`
+	FirstName      string    `bson:"first_name" json:"first_name"`
+	LastName       string    `bson:"last_name" json:"last_name"`
+	Email          string    `bson:"email" json:"email"`
+	Role           string    `bson:"role" json:"role"`
+	Disabled       bool      `bson:"disabled" json:"disabled"`
+	EmailVerified  bool      `bson:"email_verified" json:"email_verified"`
+	TOTPEnabled    bool      `bson:"totp_enabled" json:"totp_enabled"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+	FavoriteGenres []Genre   `bson:"favorite_genres" json:"favorite_genres"`
+}
+
+type RoleUpdate struct {
+	Role string `json:"role" validate:"required,oneof=ADMIN USER"`
+}
+
+type CredentialReset struct {
+	UserID            string `json:"user_id"`
+	TemporaryPassword string `json:"temporary_password"`
+}
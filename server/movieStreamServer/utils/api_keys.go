@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	apiKeyPrefix = "msk"
+	// last_used_at is only written this often to keep key checks cheap.
+	apiKeyUsageGranularity = time.Minute
+)
+
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+// GetAPIKey extracts an API key from either an "Authorization: ApiKey <key>"
+// header or an X-API-Key header.
+func GetAPIKey(headers http.Header) string {
+	if key := headers.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	parts := strings.Fields(headers.Get("Authorization"))
+	if len(parts) == 2 && strings.EqualFold(parts[0], "ApiKey") {
+		return parts[1]
+	}
+	return ""
+}
+
+// CreateAPIKey issues a key of the form msk_<key id>_<secret>. The key id
+// locates the record; only a hash of the full key is stored.
+func CreateAPIKey(name string, scopes []string, createdBy, dbName string) (*modelStructs.APIKeyCreated, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keyId, err := MakeRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := MakeRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	keyId = keyId[:16]
+	key := apiKeyPrefix + "_" + keyId + "_" + secret
+
+	record := modelStructs.APIKey{
+		KeyID:     keyId,
+		Name:      name,
+		KeyHash:   HashToken(key),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	collection := database.OpenCollection("api_keys", dbName)
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		return nil, err
+	}
+	return &modelStructs.APIKeyCreated{APIKey: record, Key: key}, nil
+}
+
+func ListAPIKeys(dbName string) ([]modelStructs.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keys := make([]modelStructs.APIKey, 0)
+
+	collection := database.OpenCollection("api_keys", dbName)
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey reports false if there is no active key with keyId.
+func RevokeAPIKey(keyId, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("api_keys", dbName)
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"key_id": keyId, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func AuthenticateAPIKey(key, dbName string) (*modelStructs.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var record modelStructs.APIKey
+
+	collection := database.OpenCollection("api_keys", dbName)
+	if err := collection.FindOne(ctx, bson.M{"key_id": parts[1], "revoked_at": nil}).Decode(&record); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashToken(key)), []byte(record.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiKeyUsageGranularity {
+		if _, err := collection.UpdateOne(ctx, bson.M{"key_id": record.KeyID}, bson.M{"$set": bson.M{"last_used_at": now}}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &record, nil
+}
+
+// GetAPIKeyOwner loads the user who created key. A key stops working once
+// its creator is deleted or disabled.
+func GetAPIKeyOwner(key *modelStructs.APIKey, dbName string) (*modelStructs.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var owner modelStructs.User
+
+	collection := database.OpenCollection("users", dbName)
+	if err := collection.FindOne(ctx, bson.M{"user_id": key.CreatedBy}).Decode(&owner); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if owner.Disabled {
+		return nil, ErrInvalidAPIKey
+	}
+	return &owner, nil
+}
+
+// APIKeyPermissions returns the key's scopes that its owner's current role
+// still grants, so a key never outlives a demotion.
+func APIKeyPermissions(key *modelStructs.APIKey, owner *modelStructs.User) []Permission {
+	granted := PermissionsForRole(owner.Role)
+
+	perms := make([]Permission, 0, len(key.Scopes))
+	for _, perm := range PermissionsFromScopes(key.Scopes) {
+		if slices.Contains(granted, perm) {
+			perms = append(perms, perm)
+		}
+	}
+	return perms
+}
+
+// APIKeyClaims describes an API key caller in the same shape as a user's
+// access token so downstream handlers and checks can treat both alike. MFA
+// reflects whether the owner has a second factor, since the key acts with
+// the owner's privileges.
+func APIKeyClaims(key *modelStructs.APIKey, owner *modelStructs.User) *AccessTokenClaims {
+	claims := &AccessTokenClaims{
+		Role:          ServiceRole,
+		EmailVerified: true,
+		MFA:           owner.TOTPEnabled,
+	}
+	claims.Subject = "apikey:" + key.KeyID
+	return claims
+}
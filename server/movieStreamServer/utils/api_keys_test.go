@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+func TestAPIKeyPermissions(t *testing.T) {
+	key := &modelStructs.APIKey{KeyID: "k1", Scopes: []string{"movie:write", "user:admin", "unknown"}}
+
+	tests := []struct {
+		name  string
+		owner modelStructs.User
+		want  []Permission
+	}{
+		{name: "admin owner", owner: modelStructs.User{Role: "ADMIN"}, want: []Permission{PermMovieWrite, PermUserAdmin}},
+		{name: "demoted owner", owner: modelStructs.User{Role: "USER"}, want: []Permission{}},
+		{name: "unknown role", owner: modelStructs.User{Role: "GUEST"}, want: []Permission{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := APIKeyPermissions(key, &tt.owner); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("APIKeyPermissions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyClaims(t *testing.T) {
+	key := &modelStructs.APIKey{KeyID: "k1"}
+
+	for _, totp := range []bool{false, true} {
+		claims := APIKeyClaims(key, &modelStructs.User{Role: "ADMIN", TOTPEnabled: totp})
+		if claims.Role != ServiceRole || claims.Subject != "apikey:k1" || claims.MFA != totp {
+			t.Errorf("APIKeyClaims(owner 2FA %v) = %+v", totp, claims)
+		}
+	}
+}
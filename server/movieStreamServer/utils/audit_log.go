@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	AuditRoleChanged      = "user.role_changed"
+	AuditUserDisabled     = "user.disabled"
+	AuditUserEnabled      = "user.enabled"
+	AuditCredentialsReset = "user.credentials_reset"
+	AuditUserUnlocked     = "user.unlocked"
+	AuditAPIKeyCreated    = "api_key.created"
+	AuditAPIKeyRevoked    = "api_key.revoked"
+	AuditMovieUpdated     = "movie.updated"
+	AuditMovieDeleted     = "movie.deleted"
+	AuditMoviesImported   = "movie.imported"
+)
+
+func RecordAudit(actorId, action, targetId string, details map[string]interface{}, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("audit_log", dbName)
+	_, err := collection.InsertOne(ctx, modelStructs.AuditEntry{
+		ActorID:   actorId,
+		Action:    action,
+		TargetID:  targetId,
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+func GetAuditLog(targetId string, limit int64, dbName string) ([]modelStructs.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if targetId != "" {
+		filter["target_id"] = targetId
+	}
+
+	entries := make([]modelStructs.AuditEntry, 0)
+
+	collection := database.OpenCollection("audit_log", dbName)
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
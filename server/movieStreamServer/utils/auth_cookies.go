@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFCookie         = "csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+	OIDCStateCookie    = "oidc_state"
+)
+
+// refreshCookiePath limits the refresh token cookie to the one route that
+// needs it.
+const refreshCookiePath = "/refresh"
+
+// oidcCookiePath limits the login state cookie to the external login routes.
+const oidcCookiePath = "/auth/"
+
+var (
+	ErrCSRFTokenMismatch = errors.New("missing or invalid CSRF token")
+	ErrOIDCStateMismatch = errors.New("login was not started from this browser")
+)
+
+// CookieConfig controls the optional cookie auth mode for browser clients.
+// When Enabled, tokens are delivered in HttpOnly cookies instead of the
+// response body, and cookie-authenticated requests that change state must
+// echo the csrf_token cookie in the X-CSRF-Token header.
+type CookieConfig struct {
+	Enabled  bool
+	Secure   bool
+	Domain   string
+	SameSite http.SameSite
+}
+
+// SetAuthCookies stores the token pair in HttpOnly cookies and issues a new
+// CSRF token in a cookie the client can read.
+func SetAuthCookies(w http.ResponseWriter, cfg CookieConfig, token, refreshToken string) error {
+	csrf, err := MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, cfg.cookie(AccessTokenCookie, token, "/", AccessTokenExpiry, true))
+	http.SetCookie(w, cfg.cookie(RefreshTokenCookie, refreshToken, refreshCookiePath, RefreshTokenExpiry, true))
+	http.SetCookie(w, cfg.cookie(CSRFCookie, csrf, "/", RefreshTokenExpiry, false))
+	return nil
+}
+
+func ClearAuthCookies(w http.ResponseWriter, cfg CookieConfig) {
+	http.SetCookie(w, cfg.cookie(AccessTokenCookie, "", "/", -1, true))
+	http.SetCookie(w, cfg.cookie(RefreshTokenCookie, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, cfg.cookie(CSRFCookie, "", "/", -1, false))
+}
+
+func (cfg CookieConfig) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSite,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(maxAge.Seconds())
+	}
+	return cookie
+}
+
+// GetCookieToken returns the value of the named auth cookie, or "" if the
+// request does not carry it.
+func GetCookieToken(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// CheckCSRF enforces the double-submit check for cookie-authenticated
+// requests. Safe methods are exempt since they must not change state.
+func CheckCSRF(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	cookie := GetCookieToken(r, CSRFCookie)
+	header := r.Header.Get(CSRFHeader)
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+	return nil
+}
+
+// SetOIDCStateCookie binds an external login to the browser that started it
+// by storing a digest of its state value. The cookie is sent regardless of
+// the auth cookie mode and must be SameSite=Lax so it survives the top-level
+// redirect back from the provider.
+func SetOIDCStateCookie(w http.ResponseWriter, cfg CookieConfig, state string) {
+	cookie := cfg.cookie(OIDCStateCookie, HashToken(state), oidcCookiePath, OIDCStateExpiry, true)
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+}
+
+func ClearOIDCStateCookie(w http.ResponseWriter, cfg CookieConfig) {
+	cookie := cfg.cookie(OIDCStateCookie, "", oidcCookiePath, -1, true)
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+}
+
+// CheckOIDCStateCookie reports whether the callback request carries the
+// cookie set for state, which stops an attacker from completing a login they
+// started in the victim's browser.
+func CheckOIDCStateCookie(r *http.Request, state string) error {
+	cookie := GetCookieToken(r, OIDCStateCookie)
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(HashToken(state))) != 1 {
+		return ErrOIDCStateMismatch
+	}
+	return nil
+}
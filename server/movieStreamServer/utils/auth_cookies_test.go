@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetAuthCookies(t *testing.T) {
+	cfg := CookieConfig{Enabled: true, Secure: true, Domain: "example.com", SameSite: http.SameSiteStrictMode}
+
+	rec := httptest.NewRecorder()
+	if err := SetAuthCookies(rec, cfg, "access", "refresh"); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		path     string
+		httpOnly bool
+	}{
+		{name: AccessTokenCookie, value: "access", path: "/", httpOnly: true},
+		{name: RefreshTokenCookie, value: "refresh", path: refreshCookiePath, httpOnly: true},
+		{name: CSRFCookie, path: "/", httpOnly: false},
+	}
+
+	for _, tt := range tests {
+		cookie, ok := cookies[tt.name]
+		if !ok {
+			t.Errorf("cookie %s not set", tt.name)
+			continue
+		}
+		if tt.value != "" && cookie.Value != tt.value {
+			t.Errorf("%s value = %q, want %q", tt.name, cookie.Value, tt.value)
+		}
+		if cookie.Value == "" {
+			t.Errorf("%s is empty", tt.name)
+		}
+		if cookie.Path != tt.path || cookie.HttpOnly != tt.httpOnly || !cookie.Secure ||
+			cookie.Domain != "example.com" || cookie.SameSite != http.SameSiteStrictMode || cookie.MaxAge <= 0 {
+			t.Errorf("unexpected %s cookie %+v", tt.name, cookie)
+		}
+	}
+}
+
+func TestClearAuthCookies(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ClearAuthCookies(rec, CookieConfig{})
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("got %d cookies, want 3", len(cookies))
+	}
+	for _, cookie := range cookies {
+		if cookie.MaxAge >= 0 || cookie.Value != "" {
+			t.Errorf("cookie %s is not cleared: %+v", cookie.Name, cookie)
+		}
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		cookie  string
+		header  string
+		wantErr bool
+	}{
+		{name: "GET is exempt", method: http.MethodGet},
+		{name: "HEAD is exempt", method: http.MethodHead},
+		{name: "OPTIONS is exempt", method: http.MethodOptions},
+		{name: "matching token", method: http.MethodPost, cookie: "token-1", header: "token-1"},
+		{name: "matching token on DELETE", method: http.MethodDelete, cookie: "token-1", header: "token-1"},
+		{name: "missing header", method: http.MethodPost, cookie: "token-1", wantErr: true},
+		{name: "missing cookie", method: http.MethodPost, header: "token-1", wantErr: true},
+		{name: "both missing", method: http.MethodPatch, wantErr: true},
+		{name: "mismatch", method: http.MethodPut, cookie: "token-1", header: "token-2", wantErr: true},
+		{name: "prefix only", method: http.MethodPost, cookie: "token-1", header: "token-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+
+			err := CheckCSRF(req)
+			if tt.wantErr && err != ErrCSRFTokenMismatch {
+				t.Errorf("CheckCSRF error = %v, want %v", err, ErrCSRFTokenMismatch)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckCSRF error = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	EmailVerificationExpiry   = 24 * time.Hour
+	emailVerificationAudience = "email-verification"
+)
+
+type EmailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// MakeEmailVerificationToken signs a token proving control of email. The
+// email is embedded so a link stops working once the address changes.
+func MakeEmailVerificationToken(userID, email string, keys *Keyring) (string, error) {
+	claims := &EmailVerificationClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "movie-streamer",
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(EmailVerificationExpiry)),
+			Subject:   userID,
+		},
+	}
+	return keys.Sign(claims)
+}
+
+func ValidateEmailVerificationToken(token string, keys *Keyring) (*EmailVerificationClaims, error) {
+	parsed, err := jwt.ParseWithClaims(token, &EmailVerificationClaims{}, keys.Keyfunc, jwt.WithAudience(emailVerificationAudience))
+	if err != nil || !parsed.Valid {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	claims, ok := parsed.Claims.(*EmailVerificationClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
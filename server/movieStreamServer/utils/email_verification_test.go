@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	keys := NewHMACKeyring("secret")
+
+	token, err := MakeEmailVerificationToken("user-1", "user@example.com", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ValidateEmailVerificationToken(token, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Access tokens share the keyring but must not pass as verification links.
+	access, err := MakeJwt(AccessTokenClaims{Role: "USER", RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, keys, AccessTokenExpiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateEmailVerificationToken(access, keys); err == nil {
+		t.Error("an access token was accepted as a verification link")
+	}
+	if _, err := ValidateEmailVerificationToken(token, NewHMACKeyring("other")); err == nil {
+		t.Error("a link signed with another key was accepted")
+	}
+}
+
+func TestEmailVerificationTokenSurvivesRotation(t *testing.T) {
+	dbName := testDatabase(t)
+
+	keys, err := LoadKeyring(AlgEdDSA, dbName, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := MakeEmailVerificationToken("user-1", "user@example.com", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateEmailVerificationToken(token, keys); err != nil {
+		t.Fatalf("link signed before the rotation rejected: %v", err)
+	}
+
+	// The retired key must outlive the link, not just access tokens.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var retired modelStructs.SigningKey
+	err = database.OpenCollection("signing_keys", dbName).
+		FindOne(ctx, bson.M{"expires_at": bson.M{"$ne": nil}}).Decode(&retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retired.ExpiresAt == nil || retired.ExpiresAt.Before(time.Now().Add(EmailVerificationExpiry-time.Minute)) {
+		t.Errorf("retired key expires at %v, before links it signed", retired.ExpiresAt)
+	}
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrUnknownGenre = errors.New("unknown genre")
+	ErrGenreInUse   = errors.New("genre is still used by movies")
+)
+
+// LoadGenres returns the genre catalog keyed by genre ID.
+func LoadGenres(dbName string) (map[int]modelStructs.Genre, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var genres []modelStructs.Genre
+
+	collection := database.OpenCollection("genres", dbName)
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &genres); err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[int]modelStructs.Genre, len(genres))
+	for _, genre := range genres {
+		catalog[genre.GenreID] = genre
+	}
+	return catalog, nil
+}
+
+// ResolveGenres checks that every genre refers to a catalog entry by ID and
+// returns them with their catalog names, dropping repeats. The names sent by
+// the client are ignored so they cannot drift from the catalog.
+func ResolveGenres(genres []modelStructs.Genre, dbName string) ([]modelStructs.Genre, error) {
+	catalog, err := LoadGenres(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return resolveGenres(genres, catalog)
+}
+
+func resolveGenres(genres []modelStructs.Genre, catalog map[int]modelStructs.Genre) ([]modelStructs.Genre, error) {
+	resolved := make([]modelStructs.Genre, 0, len(genres))
+	seen := map[int]bool{}
+	var unknown []int
+
+	for _, genre := range genres {
+		known, ok := catalog[genre.GenreID]
+		if !ok {
+			unknown = append(unknown, genre.GenreID)
+			continue
+		}
+		if !seen[known.GenreID] {
+			seen[known.GenreID] = true
+			resolved = append(resolved, known)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownGenre, unknown)
+	}
+	return resolved, nil
+}
+
+// SplitGenres resolves the genres the catalog knows, as ResolveGenres does,
+// and returns the ones it does not know separately instead of failing. It is
+// meant for drafts from outside sources whose genre lists an admin reviews.
+func SplitGenres(genres []modelStructs.Genre, dbName string) (known, unknown []modelStructs.Genre, err error) {
+	catalog, err := LoadGenres(dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+	known, unknown = splitGenres(genres, catalog)
+	return known, unknown, nil
+}
+
+func splitGenres(genres []modelStructs.Genre, catalog map[int]modelStructs.Genre) (known, unknown []modelStructs.Genre) {
+	kept := make([]modelStructs.Genre, 0, len(genres))
+	unknown = make([]modelStructs.Genre, 0)
+	for _, genre := range genres {
+		if _, ok := catalog[genre.GenreID]; ok {
+			kept = append(kept, genre)
+		} else {
+			unknown = append(unknown, genre)
+		}
+	}
+
+	// Every kept genre is in the catalog, so this cannot fail.
+	known, _ = resolveGenres(kept, catalog)
+	return known, unknown
+}
+
+// RenameGenre changes a genre's name in the catalog and in every movie and
+// user that embeds it.
+func RenameGenre(genreId int, name, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("genres", dbName)
+	result, err := collection.UpdateOne(ctx, bson.M{"genre_id": genreId}, bson.M{"$set": bson.M{"genre_name": name}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	arrayFilters := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"g.genre_id": genreId}},
+	})
+
+	if _, err := database.OpenCollection("movies", dbName).UpdateMany(ctx,
+		bson.M{"genre.genre_id": genreId},
+		bson.M{
+			"$set": bson.M{"genre.$[g].genre_name": name, "updated_at": time.Now().UTC()},
+			"$inc": bson.M{"version": 1},
+		},
+		arrayFilters,
+	); err != nil {
+		return err
+	}
+
+	_, err = database.OpenCollection("users", dbName).UpdateMany(ctx,
+		bson.M{"favorite_genres.genre_id": genreId},
+		bson.M{"$set": bson.M{"favorite_genres.$[g].genre_name": name}},
+		arrayFilters,
+	)
+	return err
+}
+
+// DeleteGenre removes a genre that no movie uses and drops it from users'
+// favorites. Genres still attached to movies must be moved off them first.
+//
+// The genre is deleted before movies are checked for it rather than after,
+// so writers resolving genres from then on reject it, and a movie written by
+// one that resolved it just before shows up in the check, which puts the
+// genre back.
+func DeleteGenre(genreId int, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("genres", dbName)
+	deleted, err := collection.FindOneAndDelete(ctx, bson.M{"genre_id": genreId}).Raw()
+	if err != nil {
+		return err
+	}
+
+	count, err := database.OpenCollection("movies", dbName).CountDocuments(ctx, bson.M{"genre.genre_id": genreId})
+	if err == nil && count > 0 {
+		err = fmt.Errorf("%w (%d movies)", ErrGenreInUse, count)
+	}
+	if err != nil {
+		if _, restoreErr := collection.InsertOne(ctx, deleted); restoreErr != nil && !mongo.IsDuplicateKeyError(restoreErr) {
+			return fmt.Errorf("%w; restoring genre %d: %w", err, genreId, restoreErr)
+		}
+		return err
+	}
+
+	_, err = database.OpenCollection("users", dbName).UpdateMany(ctx,
+		bson.M{"favorite_genres.genre_id": genreId},
+		bson.M{"$pull": bson.M{"favorite_genres": bson.M{"genre_id": genreId}}},
+	)
+	return err
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestResolveGenres(t *testing.T) {
+	action := modelStructs.Genre{GenreID: 28, GenreName: "Action"}
+	drama := modelStructs.Genre{GenreID: 18, GenreName: "Drama"}
+	catalog := map[int]modelStructs.Genre{28: action, 18: drama}
+
+	tests := []struct {
+		name    string
+		in      []modelStructs.Genre
+		want    []modelStructs.Genre
+		wantErr error
+	}{
+		{name: "empty", in: nil, want: []modelStructs.Genre{}},
+		{name: "canonical", in: []modelStructs.Genre{action, drama}, want: []modelStructs.Genre{action, drama}},
+		{name: "names taken from the catalog", in: []modelStructs.Genre{{GenreID: 28, GenreName: "action!"}, {GenreID: 18}}, want: []modelStructs.Genre{action, drama}},
+		{name: "duplicates dropped in order", in: []modelStructs.Genre{drama, action, {GenreID: 18, GenreName: "Dramas"}}, want: []modelStructs.Genre{drama, action}},
+		{name: "unknown ID", in: []modelStructs.Genre{action, {GenreID: 99, GenreName: "Action"}}, wantErr: ErrUnknownGenre},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveGenres(tt.in, catalog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveGenres error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveGenres = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitGenres(t *testing.T) {
+	action := modelStructs.Genre{GenreID: 28, GenreName: "Action"}
+	catalog := map[int]modelStructs.Genre{28: action}
+
+	tests := []struct {
+		name        string
+		in          []modelStructs.Genre
+		wantKnown   []modelStructs.Genre
+		wantUnknown []modelStructs.Genre
+	}{
+		{name: "empty", in: nil, wantKnown: []modelStructs.Genre{}, wantUnknown: []modelStructs.Genre{}},
+		{
+			name:        "known resolved, unknown kept aside",
+			in:          []modelStructs.Genre{{GenreID: 28, GenreName: "Action & Adventure"}, {GenreID: 10759, GenreName: "Action & Adventure"}},
+			wantKnown:   []modelStructs.Genre{action},
+			wantUnknown: []modelStructs.Genre{{GenreID: 10759, GenreName: "Action & Adventure"}},
+		},
+		{
+			name:        "duplicates dropped",
+			in:          []modelStructs.Genre{action, action},
+			wantKnown:   []modelStructs.Genre{action},
+			wantUnknown: []modelStructs.Genre{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known, unknown := splitGenres(tt.in, catalog)
+			if !reflect.DeepEqual(known, tt.wantKnown) || !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("splitGenres = %v, %v, want %v, %v", known, unknown, tt.wantKnown, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestDeleteGenre(t *testing.T) {
+	dbName := testDatabase(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	action := modelStructs.Genre{GenreID: 28, GenreName: "Action"}
+	drama := modelStructs.Genre{GenreID: 18, GenreName: "Drama"}
+
+	genres := database.OpenCollection("genres", dbName)
+	for _, genre := range []modelStructs.Genre{action, drama} {
+		if _, err := genres.InsertOne(ctx, genre); err != nil {
+			t.Fatal(err)
+		}
+	}
+	movie := modelStructs.Movie{ImdbID: "tt1", Title: "One", Genre: []modelStructs.Genre{action}}
+	if _, err := database.OpenCollection("movies", dbName).InsertOne(ctx, movie); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteGenre(28, dbName); !errors.Is(err, ErrGenreInUse) {
+		t.Errorf("DeleteGenre(in use) error = %v, want %v", err, ErrGenreInUse)
+	}
+	if n, _ := genres.CountDocuments(ctx, bson.M{"genre_id": 28}); n != 1 {
+		t.Errorf("genre in use was not restored")
+	}
+
+	if err := DeleteGenre(18, dbName); err != nil {
+		t.Errorf("DeleteGenre(unused) error = %v", err)
+	}
+	if err := DeleteGenre(18, dbName); err != mongo.ErrNoDocuments {
+		t.Errorf("DeleteGenre(missing) error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+}
@@ -2,49 +2,42 @@ package utils
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func GetUserFavGenre(userId, dbName string) ([]string, error) {
+// GetUserFavGenreIDs returns the IDs of the user's favorite genres. Matching
+// on IDs rather than names keeps recommendations working across renames.
+func GetUserFavGenreIDs(userId, dbName string) ([]int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	collection := database.OpenCollection("users", dbName)
 
 	opts := options.FindOne().SetProjection(bson.M{
-		"favorite_genres.genre_name": 1,
-		"_id":                        0,
+		"favorite_genres.genre_id": 1,
+		"_id":                      0,
 	})
 
-	var result bson.M
+	var result struct {
+		FavoriteGenres []modelStructs.Genre `bson:"favorite_genres"`
+	}
 	err := collection.FindOne(ctx, bson.M{"user_id": userId}, opts).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return []string{}, nil
+			return []int{}, nil
 		}
 		return nil, err
 	}
 
-	favGenre, ok := result["favorite_genres"].(primitive.A)
-	if !ok {
-		return []string{}, errors.New("Unable to retrieve favorite genres")
-	}
-
-	var genres []string
-
-	for _, item := range favGenre {
-		if genreMap, ok := item.(bson.M); ok {
-			if name, ok := genreMap["genre_name"].(string); ok {
-				genres = append(genres, name)
-			}
-		}
+	genres := make([]int, 0, len(result.FavoriteGenres))
+	for _, genre := range result.FavoriteGenres {
+		genres = append(genres, genre.GenreID)
 	}
 
 	return genres, nil
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -9,17 +11,21 @@ import (
 	"github.com/firebase/genkit/go/genkit"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
 	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func GetRankings(dbName string) ([]modelStructs.Ranking, error) {
+// GetRankings returns the ranking scale in display order.
+func GetRankings(dbName string) ([]modelStructs.RankingLevel, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var rankings []modelStructs.Ranking
+	rankings := make([]modelStructs.RankingLevel, 0)
 	collection := database.OpenCollection("rankings", dbName)
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	sort := primitive.D{{Key: "order", Value: 1}, {Key: "ranking_value", Value: 1}}
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(sort))
 	if err != nil {
 		return nil, err
 	}
@@ -31,16 +37,22 @@ func GetRankings(dbName string) ([]modelStructs.Ranking, error) {
 	return rankings, nil
 }
 
-func GetReviewRanking(g *genkit.Genkit, basePrompt, dbName, admin_review string) (string, int, error) {
+// ErrRankingProvider wraps failures of the model that ranks reviews.
+var ErrRankingProvider = errors.New("review ranking provider failed")
+
+// GetReviewRanking asks the model to place admin_review on the visible part
+// of the ranking scale. An answer naming no visible level is reported as
+// ErrUnknownRanking rather than stored.
+func GetReviewRanking(g *genkit.Genkit, basePrompt, dbName, admin_review string) (modelStructs.Ranking, error) {
 	rankings, err := GetRankings(dbName)
 	if err != nil {
-		return "", 0, err
+		return modelStructs.Ranking{}, err
 	}
 
 	str := ""
 
 	for _, ranking := range rankings {
-		if ranking.RankingValue != 999 {
+		if !ranking.Hidden {
 			str = str + ranking.RankingName + ","
 		}
 	}
@@ -64,16 +76,29 @@ func GetReviewRanking(g *genkit.Genkit, basePrompt, dbName, admin_review string)
 	// }
 	res, err := genkit.Generate(context.Background(), g, ai.WithPrompt(prompt+admin_review))
 	if err != nil {
-		return "", 0, err
+		return modelStructs.Ranking{}, fmt.Errorf("%w: %w", ErrRankingProvider, err)
 	}
 
-	rankingValue := 0
+	return matchReviewRanking(res.Text(), rankings)
+}
+
+// matchReviewRanking finds the visible level the model's answer names and
+// resolves it against the visible levels only, so hidden levels such as
+// "not ranked" can never be assigned from a review.
+func matchReviewRanking(answer string, rankings []modelStructs.RankingLevel) (modelStructs.Ranking, error) {
+	answer = strings.TrimSpace(answer)
 
+	visible := make(map[int]modelStructs.RankingLevel, len(rankings))
 	for _, ranking := range rankings {
-		if ranking.RankingName == res.Text() {
-			rankingValue = ranking.RankingValue
-			break
+		if !ranking.Hidden {
+			visible[ranking.RankingValue] = ranking
+		}
+	}
+
+	for _, ranking := range visible {
+		if strings.EqualFold(ranking.RankingName, answer) {
+			return resolveRanking(modelStructs.Ranking{RankingValue: ranking.RankingValue}, visible)
 		}
 	}
-	return res.Text(), rankingValue, nil
+	return modelStructs.Ranking{}, fmt.Errorf("%w: %q", ErrUnknownRanking, answer)
 }
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMatchReviewRanking(t *testing.T) {
+	rankings := []modelStructs.RankingLevel{
+		{RankingValue: 1, RankingName: "Excellent"},
+		{RankingValue: 2, RankingName: "Good"},
+		{RankingValue: 999, RankingName: "Not_Ranked", Hidden: true},
+	}
+
+	tests := []struct {
+		answer  string
+		want    modelStructs.Ranking
+		wantErr error
+	}{
+		{answer: "Excellent", want: modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"}},
+		{answer: " good\n", want: modelStructs.Ranking{RankingValue: 2, RankingName: "Good"}},
+		{answer: "Not_Ranked", wantErr: ErrUnknownRanking},
+		{answer: "Brilliant", wantErr: ErrUnknownRanking},
+		{answer: "", wantErr: ErrUnknownRanking},
+	}
+
+	for _, tt := range tests {
+		got, err := matchReviewRanking(tt.answer, rankings)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("matchReviewRanking(%q) error = %v, want %v", tt.answer, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("matchReviewRanking(%q) = %+v, want %+v", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteRanking(t *testing.T) {
+	dbName := testDatabase(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rankings := database.OpenCollection("rankings", dbName)
+	for _, level := range []modelStructs.RankingLevel{{RankingValue: 1, RankingName: "Excellent"}, {RankingValue: 2, RankingName: "Good"}} {
+		if _, err := rankings.InsertOne(ctx, level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	movie := modelStructs.Movie{ImdbID: "tt1", Title: "One", Ranking: modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"}}
+	if _, err := database.OpenCollection("movies", dbName).InsertOne(ctx, movie); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteRanking(1, dbName); !errors.Is(err, ErrRankingInUse) {
+		t.Errorf("DeleteRanking(in use) error = %v, want %v", err, ErrRankingInUse)
+	}
+	if n, _ := rankings.CountDocuments(ctx, bson.M{"ranking_value": 1}); n != 1 {
+		t.Errorf("ranking in use was not restored")
+	}
+
+	if err := DeleteRanking(2, dbName); err != nil {
+		t.Errorf("DeleteRanking(unused) error = %v", err)
+	}
+	if err := DeleteRanking(2, dbName); err != mongo.ErrNoDocuments {
+		t.Errorf("DeleteRanking(missing) error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+}
@@ -1,10 +1,29 @@
 package utils
 
-import "github.com/alexedwards/argon2id"
+import (
+	"errors"
+	"sync"
+
+	"github.com/alexedwards/argon2id"
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+var passwordParams = *argon2id.DefaultParams
+
+// SetPasswordParams changes the argon2id parameters used for new hashes.
+// Existing hashes keep verifying and are upgraded on the next login.
+func SetPasswordParams(params argon2id.Params) {
+	passwordParams = params
+}
+
+func DefaultPasswordParams() argon2id.Params {
+	return *argon2id.DefaultParams
+}
 
 func HashPassword(password string) (string, error) {
 
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	hash, err := argon2id.CreateHash(password, &passwordParams)
 	if err != nil {
 		return "", err
 	}
@@ -12,10 +31,49 @@ func HashPassword(password string) (string, error) {
 }
 
 func CheckPasswordAndHash(password, hash string) error {
-	_, err := argon2id.ComparePasswordAndHash(password, hash)
+	match, err := argon2id.ComparePasswordAndHash(password, hash)
 	if err != nil {
 		return err
 	}
+	if !match {
+		return ErrPasswordMismatch
+	}
 
 	return nil
 }
+
+// PasswordNeedsRehash reports whether hash was created with parameters
+// weaker than the current policy in any dimension.
+func PasswordNeedsRehash(hash string) bool {
+	params, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false
+	}
+
+	return params.Memory < passwordParams.Memory ||
+		params.Iterations < passwordParams.Iterations ||
+		params.Parallelism < passwordParams.Parallelism ||
+		params.SaltLength < passwordParams.SaltLength ||
+		params.KeyLength < passwordParams.KeyLength
+}
+
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("movie-streamer-dummy-password")
+	return hash
+})
+
+// CheckDummyPassword spends the same effort as CheckPasswordAndHash so that
+// logins for unknown emails take as long as logins with a wrong password.
+func CheckDummyPassword(password string) {
+	CheckPasswordAndHash(password, dummyHash())
+}
+
+// MakeTemporaryPassword returns a random password suitable for handing to a
+// user out of band after an admin resets their credentials.
+func MakeTemporaryPassword() (string, error) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return token[:20], nil
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+// SearchTerms extracts the lowercased words of a $text search string,
+// dropping negated terms and the quotes around phrases.
+func SearchTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		word = strings.ToLower(strings.TrimFunc(word, isNotWordRune))
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Highlight splits text into matched and unmatched fragments. MongoDB stems
+// search terms, so a word also counts as a match when it and a term are
+// prefixes of one another and at least three letters long, which catches
+// most inflections. It returns nil when nothing in text matches.
+func Highlight(text string, terms []string) []modelStructs.HighlightFragment {
+	var fragments []modelStructs.HighlightFragment
+	matched := false
+
+	appendFragment := func(s string, match bool) {
+		if s == "" {
+			return
+		}
+		if n := len(fragments); n > 0 && fragments[n-1].Match == match {
+			fragments[n-1].Text += s
+			return
+		}
+		fragments = append(fragments, modelStructs.HighlightFragment{Text: s, Match: match})
+	}
+
+	rest := text
+	for rest != "" {
+		start := strings.IndexFunc(rest, func(r rune) bool { return !isNotWordRune(r) })
+		if start < 0 {
+			appendFragment(rest, false)
+			break
+		}
+		appendFragment(rest[:start], false)
+		rest = rest[start:]
+
+		end := strings.IndexFunc(rest, isNotWordRune)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		if matchesTerm(strings.ToLower(word), terms) {
+			matched = true
+			appendFragment(word, true)
+		} else {
+			appendFragment(word, false)
+		}
+	}
+
+	if !matched {
+		return nil
+	}
+	return fragments
+}
+
+func matchesTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if word == term {
+			return true
+		}
+		n := commonPrefix(word, term)
+		if n >= 3 && (n == len(word) || n == len(term)) {
+			return true
+		}
+	}
+	return false
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
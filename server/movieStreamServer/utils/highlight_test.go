@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{q: "", want: nil},
+		{q: "Star Wars", want: []string{"star", "wars"}},
+		{q: `"dark knight" rises`, want: []string{"dark", "knight", "rises"}},
+		{q: "alien -covenant", want: []string{"alien"}},
+		{q: "  matrix,  reloaded! ", want: []string{"matrix", "reloaded"}},
+		{q: "-- ...", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := SearchTerms(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	type frag = modelStructs.HighlightFragment
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []frag
+	}{
+		{
+			name:  "no match",
+			text:  "The Godfather",
+			terms: []string{"alien"},
+			want:  nil,
+		},
+		{
+			name:  "exact word, any case",
+			text:  "Star Wars",
+			terms: []string{"star"},
+			want:  []frag{{Text: "Star", Match: true}, {Text: " Wars"}},
+		},
+		{
+			name:  "every matching word",
+			text:  "Star Wars: A New Hope",
+			terms: []string{"star", "wars"},
+			want:  []frag{{Text: "Star", Match: true}, {Text: " "}, {Text: "Wars", Match: true}, {Text: ": A New Hope"}},
+		},
+		{
+			name:  "stemmed forms",
+			text:  "Running with the runners",
+			terms: []string{"run"},
+			want:  []frag{{Text: "Running", Match: true}, {Text: " with the "}, {Text: "runners", Match: true}},
+		},
+		{
+			name:  "term longer than word",
+			text:  "The Dark Knight",
+			terms: []string{"knights"},
+			want:  []frag{{Text: "The Dark "}, {Text: "Knight", Match: true}},
+		},
+		{
+			name:  "short prefixes do not match",
+			text:  "An Android",
+			terms: []string{"an"},
+			want:  []frag{{Text: "An", Match: true}, {Text: " Android"}},
+		},
+		{
+			name:  "unicode letters",
+			text:  "Amélie à Paris",
+			terms: []string{"amélie"},
+			want:  []frag{{Text: "Amélie", Match: true}, {Text: " à Paris"}},
+		},
+		{
+			name:  "punctuation kept",
+			text:  "(Alien)",
+			terms: []string{"alien"},
+			want:  []frag{{Text: "("}, {Text: "Alien", Match: true}, {Text: ")"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Highlight = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BodyETag derives a strong ETag from a response body.
+func BodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// VersionETag derives an ETag from a document version counter.
+func VersionETag(version int64) string {
+	return `"v` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ListETag derives a weak ETag for a listing from the request that selects
+// it (key, typically path and query) and the count and newest modification
+// time of the items it is drawn from, without encoding the listing itself.
+func ListETag(key string, count int64, lastModified time.Time) string {
+	sum := sha256.Sum256([]byte(key + "\n" + strconv.FormatInt(count, 10) + "\n" + strconv.FormatInt(lastModified.UnixNano(), 10)))
+	return `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified evaluates If-None-Match and, only when that is absent,
+// If-Modified-Since, as RFC 9110 prescribes for GET and HEAD.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return ETagMatches(inm, etag, true)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// ETagMatches reports whether etag is in the comma-separated list header,
+// which may also be "*". Weak comparison ignores the W/ prefix, as
+// If-None-Match requires; If-Match needs strong comparison.
+func ETagMatches(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
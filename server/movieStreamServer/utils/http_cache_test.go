@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		weak   bool
+		want   bool
+	}{
+		{header: `"a"`, etag: `"a"`, want: true},
+		{header: `"a"`, etag: `"b"`},
+		{header: `"b", "a"`, etag: `"a"`, want: true},
+		{header: `"b","c"`, etag: `"a"`},
+		{header: `*`, etag: `"a"`, want: true},
+		{header: `*`, etag: ``},
+		{header: `""`, etag: ``},
+		{header: `W/"a"`, etag: `"a"`, weak: true, want: true},
+		{header: `"a"`, etag: `W/"a"`, weak: true, want: true},
+		{header: `W/"a"`, etag: `W/"a"`, weak: true, want: true},
+		{header: `W/"a"`, etag: `"a"`},
+		{header: `W/"a"`, etag: `W/"a"`},
+		{header: `W/"b", "a"`, etag: `"a"`, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := ETagMatches(tt.header, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("ETagMatches(%q, %q, weak=%v) = %v, want %v", tt.header, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	at := func(d time.Duration) string { return modified.Add(d).Format(http.TimeFormat) }
+
+	tests := []struct {
+		name         string
+		inm          string
+		ims          string
+		lastModified time.Time
+		want         bool
+	}{
+		{name: "no conditions", lastModified: modified},
+		{name: "etag matches", inm: `"a"`, lastModified: modified, want: true},
+		{name: "weak etag matches", inm: `W/"a"`, lastModified: modified, want: true},
+		{name: "etag differs", inm: `"b"`, lastModified: modified},
+		{name: "etag wins over date", inm: `"b"`, ims: at(time.Hour), lastModified: modified},
+		{name: "same second", ims: at(0), lastModified: modified, want: true},
+		{name: "later date", ims: at(time.Hour), lastModified: modified, want: true},
+		{name: "earlier date", ims: at(-time.Second), lastModified: modified},
+		{name: "bad date", ims: "yesterday", lastModified: modified},
+		{name: "unknown modification time", ims: at(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/movies", nil)
+			if tt.inm != "" {
+				r.Header.Set("If-None-Match", tt.inm)
+			}
+			if tt.ims != "" {
+				r.Header.Set("If-Modified-Since", tt.ims)
+			}
+
+			if got := NotModified(r, `"a"`, tt.lastModified); got != tt.want {
+				t.Errorf("NotModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETags(t *testing.T) {
+	if BodyETag([]byte("a")) != BodyETag([]byte("a")) || BodyETag([]byte("a")) == BodyETag([]byte("b")) {
+		t.Error("BodyETag is not derived from the body")
+	}
+	if got := VersionETag(3); got != `"v3"` {
+		t.Errorf("VersionETag(3) = %s", got)
+	}
+
+	now := time.Now()
+	etag := ListETag("/movies?genre=28", 10, now)
+	if etag[:3] != `W/"` {
+		t.Errorf("ListETag = %s, want a weak ETag", etag)
+	}
+
+	for name, other := range map[string]string{
+		"query":    ListETag("/movies?genre=18", 10, now),
+		"count":    ListETag("/movies?genre=28", 9, now),
+		"modified": ListETag("/movies?genre=28", 10, now.Add(time.Millisecond)),
+	} {
+		if other == etag {
+			t.Errorf("ListETag does not change with the %s", name)
+		}
+	}
+	if ListETag("/movies?genre=28", 10, now) != etag {
+		t.Error("ListETag is not stable")
+	}
+}
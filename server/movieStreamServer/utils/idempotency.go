@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const IdempotencyKeyExpiry = 24 * time.Hour
+
+// BeginIdempotentRequest claims key for a new request. If the key was
+// already claimed it returns the existing record instead, which is either
+// still in progress or holds the response to replay.
+func BeginIdempotentRequest(key, requestHash, dbName string) (*modelStructs.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	collection := database.OpenCollection("idempotency_keys", dbName)
+
+	_, err := collection.InsertOne(ctx, modelStructs.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(IdempotencyKeyExpiry),
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var record modelStructs.IdempotencyRecord
+	if err := collection.FindOne(ctx, bson.M{"key": key}).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// idempotencySkipHeaders are response headers that belong to one particular
+// response and must not be replayed: cookies would hand out credentials
+// again and the rest are recomputed for every response.
+var idempotencySkipHeaders = []string{"Set-Cookie", "Date", "Content-Length", "Connection", "Transfer-Encoding", "Trailer"}
+
+// CompleteIdempotentRequest stores the response to replay for key, including
+// its headers (ETag, Location, ...) so a replay is indistinguishable from the
+// original.
+func CompleteIdempotentRequest(key string, status int, header http.Header, body []byte, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	headers := map[string][]string(header.Clone())
+	for _, name := range idempotencySkipHeaders {
+		delete(headers, name)
+	}
+
+	collection := database.OpenCollection("idempotency_keys", dbName)
+	_, err := collection.UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": bson.M{
+		"completed":   true,
+		"status_code": status,
+		"headers":     headers,
+		"body":        body,
+	}})
+	return err
+}
+
+// AbortIdempotentRequest releases key so the request can be retried, for
+// failures that left nothing behind worth replaying.
+func AbortIdempotentRequest(key, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("idempotency_keys", dbName)
+	_, err := collection.DeleteOne(ctx, bson.M{"key": key, "completed": false})
+	return err
+}
@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -12,31 +11,38 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const AccessTokenExpiry = time.Hour
+
 type AccessTokenClaims struct {
-	Role string
+	Role          string
+	SessionID     string `json:"sid,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	MFA           bool   `json:"mfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func MakeJwt(userID, secret, role string, expiry time.Duration) (string, error) {
-
-	claims := &AccessTokenClaims{
-		Role: role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "movie-streamer",
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiry)),
-			Subject:   userID,
-		},
+// MakeJwt signs claims as an access token. Callers fill in the subject and
+// custom claims; issuer, lifetime and jti are set here.
+func MakeJwt(claims AccessTokenClaims, keys *Keyring, expiry time.Duration) (string, error) {
+	jti, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+
+	now := time.Now().UTC()
+	claims.Issuer = "movie-streamer"
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+	claims.ID = jti
+
+	return keys.Sign(&claims)
 }
 
 func MakeRefreshToken() (string, error) {
 	token := make([]byte, 32)
 	_, err := rand.Read(token)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	return hex.EncodeToString(token), nil
 }
@@ -55,13 +61,8 @@ func GetBearerToken(headers http.Header) (string, error) {
 	return parts[1], nil
 }
 
-func ValidateJwt(jwtToken, jwtSecret string) (*AccessTokenClaims, error) {
-	token, err := jwt.ParseWithClaims(jwtToken, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
+func ValidateJwt(jwtToken string, keys *Keyring) (*AccessTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(jwtToken, &AccessTokenClaims{}, keys.Keyfunc)
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid or expired token")
 	}
@@ -71,5 +72,11 @@ func ValidateJwt(jwtToken, jwtSecret string) (*AccessTokenClaims, error) {
 		return nil, errors.New("invalid token claims")
 	}
 
+	// Access tokens carry no audience; anything with one was minted for
+	// another purpose, such as email verification.
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token claims")
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// retiredKeyLifetime is how long a retired key keeps verifying: as long as
+// the longest-lived token the keyring signs, currently verification links.
+const retiredKeyLifetime = max(AccessTokenExpiry, EmailVerificationExpiry)
+
+type keyringKey struct {
+	kid       string
+	alg       string
+	signKey   crypto.PrivateKey
+	verifyKey crypto.PublicKey
+	createdAt time.Time
+}
+
+// Keyring holds the key used to sign new access tokens along with every key
+// whose tokens may still be in circulation. In HS256 mode it wraps the shared
+// JWT_SECRET; in RS256/EdDSA mode keys are persisted in the signing_keys
+// collection so every server instance signs and verifies with the same set.
+type Keyring struct {
+	mu       sync.RWMutex
+	alg      string
+	dbName   string
+	rotation time.Duration
+	keys     []keyringKey // newest first
+}
+
+func NewHMACKeyring(secret string) *Keyring {
+	return &Keyring{
+		alg: AlgHS256,
+		keys: []keyringKey{{
+			alg:       AlgHS256,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}},
+	}
+}
+
+// LoadKeyring loads the asymmetric signing keys for alg from the database,
+// generating the first key if none exist yet.
+func LoadKeyring(alg, dbName string, rotation time.Duration) (*Keyring, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+
+	k := &Keyring{alg: alg, dbName: dbName, rotation: rotation}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	if err := k.rotateIfDue(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// StartRotation periodically picks up keys created by other instances and
+// rotates the signing key once it is older than the rotation interval.
+func (k *Keyring) StartRotation(ctx context.Context, every time.Duration) {
+	if k.alg == AlgHS256 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.reload(); err != nil {
+					log.Printf("Failed to reload signing keys: %v", err)
+					continue
+				}
+				if err := k.rotateIfDue(); err != nil {
+					log.Printf("Failed to rotate signing key: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (k *Keyring) reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var records []modelStructs.SigningKey
+	collection := database.OpenCollection("signing_keys", k.dbName)
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"expires_at": nil},
+		bson.M{"expires_at": bson.M{"$gt": time.Now().UTC()}},
+	}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return err
+	}
+
+	keys := make([]keyringKey, 0, len(records))
+	for _, record := range records {
+		key, err := parseSigningKey(record)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *Keyring) rotateIfDue() error {
+	k.mu.RLock()
+	due := len(k.keys) == 0 || k.keys[0].alg != k.alg || time.Since(k.keys[0].createdAt) >= k.rotation
+	k.mu.RUnlock()
+
+	if !due {
+		return nil
+	}
+	return k.Rotate()
+}
+
+// Rotate generates a new signing key and retires the current ones. Retired
+// keys stay available for verification until the tokens they signed expire.
+func (k *Keyring) Rotate() error {
+	if k.alg == AlgHS256 {
+		return errors.New("HS256 keys cannot be rotated")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	record, err := generateSigningKey(k.alg)
+	if err != nil {
+		return err
+	}
+
+	collection := database.OpenCollection("signing_keys", k.dbName)
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"expires_at": nil},
+		bson.M{"$set": bson.M{"expires_at": record.CreatedAt.Add(retiredKeyLifetime)}},
+	); err != nil {
+		return err
+	}
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		return err
+	}
+
+	return k.reload()
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.keys) == 0 {
+		return "", errors.New("no signing key available")
+	}
+	key := k.keys[0]
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header and
+// rejects tokens whose algorithm does not match the key they name.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.kid != kid {
+			continue
+		}
+		if token.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.verifyKey, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// JWKS returns the public half of every verification key. It is empty in
+// HS256 mode since the shared secret must never be published.
+func (k *Keyring) JWKS() modelStructs.JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := modelStructs.JWKS{Keys: make([]modelStructs.JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, modelStructs.JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.alg,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, modelStructs.JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.alg,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+func generateSigningKey(alg string) (modelStructs.SigningKey, error) {
+	var private crypto.PrivateKey
+	var err error
+
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+	if err != nil {
+		return modelStructs.SigningKey{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return modelStructs.SigningKey{}, err
+	}
+
+	kid, err := MakeRefreshToken()
+	if err != nil {
+		return modelStructs.SigningKey{}, err
+	}
+
+	return modelStructs.SigningKey{
+		Kid:        kid[:16],
+		Alg:        alg,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+func parseSigningKey(record modelStructs.SigningKey) (keyringKey, error) {
+	block, _ := pem.Decode([]byte(record.PrivateKey))
+	if block == nil {
+		return keyringKey{}, fmt.Errorf("signing key %s: invalid PEM", record.Kid)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return keyringKey{}, fmt.Errorf("signing key %s: %v", record.Kid, err)
+	}
+
+	key := keyringKey{
+		kid:       record.Kid,
+		alg:       record.Alg,
+		signKey:   private,
+		createdAt: record.CreatedAt,
+	}
+
+	switch p := private.(type) {
+	case *rsa.PrivateKey:
+		key.verifyKey = &p.PublicKey
+	case ed25519.PrivateKey:
+		key.verifyKey = p.Public()
+	default:
+		return keyringKey{}, fmt.Errorf("signing key %s: unsupported key type %T", record.Kid, private)
+	}
+	return key, nil
+}
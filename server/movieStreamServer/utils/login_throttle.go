@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type throttlePolicy struct {
+	freeFailures int           // failures allowed before backoff starts
+	maxBackoff   time.Duration // cap on the exponential delay
+	lockoutAfter int           // failures that trigger a lockout
+	lockout      time.Duration
+}
+
+var (
+	emailThrottle = throttlePolicy{freeFailures: 3, maxBackoff: time.Minute, lockoutAfter: 10, lockout: 15 * time.Minute}
+	ipThrottle    = throttlePolicy{freeFailures: 20, maxBackoff: time.Minute, lockoutAfter: 100, lockout: 15 * time.Minute}
+
+	// Second-factor failures are counted per user across challenges and are
+	// not reset by a correct password, so a stolen password cannot be used to
+	// farm fresh challenges for guessing TOTP codes.
+	twoFactorThrottle = throttlePolicy{freeFailures: 3, maxBackoff: time.Minute, lockoutAfter: 10, lockout: time.Hour}
+
+	// Every password reset request counts, successful or not, and on counters
+	// of its own so that flooding a victim with reset mail cannot also lock
+	// them out of logging in.
+	resetEmailThrottle = throttlePolicy{freeFailures: 3, maxBackoff: 15 * time.Minute, lockoutAfter: 10, lockout: time.Hour}
+	resetIPThrottle    = throttlePolicy{freeFailures: 20, maxBackoff: 15 * time.Minute, lockoutAfter: 100, lockout: time.Hour}
+)
+
+// Failure counters are forgotten after a day without further failures.
+const loginAttemptRetention = 24 * time.Hour
+
+func (p throttlePolicy) delay(failures int) time.Duration {
+	if failures >= p.lockoutAfter {
+		return p.lockout
+	}
+	if failures <= p.freeFailures {
+		return 0
+	}
+
+	delay := time.Second << (failures - p.freeFailures - 1)
+	if delay > p.maxBackoff || delay <= 0 {
+		delay = p.maxBackoff
+	}
+	return delay
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func twoFactorThrottleKey(userId string) string {
+	return "2fa:" + userId
+}
+
+func resetEmailThrottleKey(email string) string {
+	return "reset:" + emailThrottleKey(email)
+}
+
+func resetIPThrottleKey(ip string) string {
+	return "reset:" + ipThrottleKey(ip)
+}
+
+// ReserveLoginAttempt counts a login attempt for email from ip before the
+// password is checked, so concurrent requests cannot all slip through while
+// the counters still allow them. The attempt is treated as a failure until
+// ReleaseLoginAttempt says otherwise. A non-zero wait means the attempt was
+// refused and the caller must retry after that long.
+func ReserveLoginAttempt(email, ip, dbName string) (time.Duration, error) {
+	wait, err := reserveAttempt(ipThrottleKey(ip), ipThrottle, dbName)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	return reserveAttempt(emailThrottleKey(email), emailThrottle, dbName)
+}
+
+// ReserveTwoFactorAttempt counts a second-factor attempt for userId before
+// the code is checked, the same way ReserveLoginAttempt does for passwords.
+func ReserveTwoFactorAttempt(userId, dbName string) (time.Duration, error) {
+	return reserveAttempt(twoFactorThrottleKey(userId), twoFactorThrottle, dbName)
+}
+
+// ReservePasswordReset counts a password reset request for email from ip.
+// Requests are never released, so the counters limit how much reset mail one
+// address can receive and one client can trigger until they age out.
+func ReservePasswordReset(email, ip, dbName string) (time.Duration, error) {
+	wait, err := reserveAttempt(resetIPThrottleKey(ip), resetIPThrottle, dbName)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	return reserveAttempt(resetEmailThrottleKey(email), resetEmailThrottle, dbName)
+}
+
+// TwoFactorRetryAfter reports how long userId must wait before another
+// login challenge may be issued. Zero means one may be issued now.
+func TwoFactorRetryAfter(userId, dbName string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var attempt modelStructs.LoginAttempt
+
+	collection := database.OpenCollection("login_attempts", dbName)
+	err := collection.FindOne(ctx, bson.M{
+		"key":             twoFactorThrottleKey(userId),
+		"next_attempt_at": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Until(*attempt.NextAttemptAt), nil
+}
+
+// ClearTwoFactorFailures resets the second-factor counter after a completed
+// two-factor login.
+func ClearTwoFactorFailures(userId, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_attempts", dbName)
+	_, err := collection.DeleteOne(ctx, bson.M{"key": twoFactorThrottleKey(userId)})
+	return err
+}
+
+// ReleaseLoginAttempt marks a reserved attempt as successful: the email
+// counter is reset and the attempt is taken back off the IP counter.
+func ReleaseLoginAttempt(email, ip, dbName string) error {
+	if err := ClearLoginFailures(email, dbName); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_attempts", dbName)
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"key": ipThrottleKey(ip), "failures": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"failures": -1}},
+	)
+	return err
+}
+
+// reserveAttempt increments the counter for key unless it is in backoff, and
+// moves next_attempt_at forward in the same update so the count and the
+// delay it implies can never disagree. When key is in backoff the filter
+// misses the existing document and the upsert collides with it on the unique
+// key index; a collision from two first attempts racing is retried.
+func reserveAttempt(key string, policy throttlePolicy, dbName string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_attempts", dbName)
+
+	for {
+		now := time.Now().UTC()
+
+		update := mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"failures":        bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+				"last_failure_at": now,
+				"expires_at":      now.Add(loginAttemptRetention),
+			}}},
+			{{Key: "$set", Value: bson.M{
+				"next_attempt_at": bson.M{"$add": bson.A{now, bson.M{"$arrayElemAt": bson.A{
+					policy.delayTable(),
+					bson.M{"$min": bson.A{"$failures", policy.lockoutAfter}},
+				}}}},
+			}}},
+		}
+
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"key": key, "next_attempt_at": bson.M{"$not": bson.M{"$gt": now}}},
+			update,
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return 0, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, err
+		}
+
+		var attempt modelStructs.LoginAttempt
+		err = collection.FindOne(ctx, bson.M{"key": key}).Decode(&attempt)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, err
+		}
+		if err == nil && attempt.NextAttemptAt != nil && attempt.NextAttemptAt.After(now) {
+			return time.Until(*attempt.NextAttemptAt), nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// delayTable lists the delay in milliseconds after each failure count up to
+// lockoutAfter, for lookup inside an update pipeline.
+func (p throttlePolicy) delayTable() bson.A {
+	table := make(bson.A, p.lockoutAfter+1)
+	for i := range table {
+		table[i] = p.delay(i).Milliseconds()
+	}
+	return table
+}
+
+// ClearLoginFailures resets the email counter after a successful login. The
+// IP counter is left alone so one valid account cannot launder an IP that is
+// stuffing credentials for others.
+func ClearLoginFailures(email, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_attempts", dbName)
+	_, err := collection.DeleteOne(ctx, bson.M{"key": emailThrottleKey(email)})
+	return err
+}
+
+// UnlockLogin lifts any lockout or backoff on email and on the second
+// factor of userId.
+func UnlockLogin(email, userId, dbName string) error {
+	if err := ClearLoginFailures(email, dbName); err != nil {
+		return err
+	}
+	return ClearTwoFactorFailures(userId, dbName)
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	policy := throttlePolicy{freeFailures: 3, maxBackoff: time.Minute, lockoutAfter: 10, lockout: 15 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 6, want: 4 * time.Second},
+		{failures: 9, want: 32 * time.Second},
+		{failures: 10, want: 15 * time.Minute},
+		{failures: 50, want: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottlePolicyDelayCapsBackoff(t *testing.T) {
+	policy := throttlePolicy{freeFailures: 0, maxBackoff: time.Minute, lockoutAfter: 200, lockout: time.Hour}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 6, want: 32 * time.Second},
+		{failures: 7, want: time.Minute},
+		{failures: 70, want: time.Minute},
+		{failures: 199, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottlePolicyDelayTable(t *testing.T) {
+	for _, policy := range []throttlePolicy{emailThrottle, ipThrottle, twoFactorThrottle} {
+		table := policy.delayTable()
+		if len(table) != policy.lockoutAfter+1 {
+			t.Fatalf("len(delayTable()) = %d, want %d", len(table), policy.lockoutAfter+1)
+		}
+		for i, ms := range table {
+			if want := policy.delay(i).Milliseconds(); ms != want {
+				t.Errorf("delayTable()[%d] = %v, want %d", i, ms, want)
+			}
+		}
+	}
+}
+
+func TestReserveLoginAttempt(t *testing.T) {
+	dbName := testDatabase(t)
+
+	const email, ip = "user@example.com", "192.0.2.1"
+
+	// emailThrottle allows three free failures; the fourth attempt is still
+	// let through but starts a one second backoff.
+	steps := []struct {
+		name     string
+		release  bool
+		wantWait bool
+	}{
+		{name: "attempt 1"},
+		{name: "attempt 2"},
+		{name: "attempt 3"},
+		{name: "attempt 4"},
+		{name: "attempt 5 during backoff", wantWait: true},
+		{name: "attempt 6 during backoff", wantWait: true},
+		{name: "success releases the email", release: true},
+		{name: "attempt after release"},
+	}
+
+	for _, step := range steps {
+		if step.release {
+			if err := ReleaseLoginAttempt(email, ip, dbName); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		wait, err := ReserveLoginAttempt(email, ip, dbName)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if (wait > 0) != step.wantWait {
+			t.Fatalf("%s: wait = %v, want wait %v", step.name, wait, step.wantWait)
+		}
+	}
+}
+
+func TestReservePasswordReset(t *testing.T) {
+	dbName := testDatabase(t)
+
+	const email, ip = "user@example.com", "192.0.2.1"
+
+	// Three requests are free; the fourth starts a backoff.
+	for i := 1; i <= 4; i++ {
+		wait, err := ReservePasswordReset(email, ip, dbName)
+		if err != nil || wait > 0 {
+			t.Fatalf("request %d: wait = %v, err = %v", i, wait, err)
+		}
+	}
+	wait, err := ReservePasswordReset(email, ip, dbName)
+	if err != nil || wait == 0 {
+		t.Fatalf("request 5: wait = %v, err = %v, want a backoff", wait, err)
+	}
+
+	// Reset requests must not count against logging in.
+	if wait, err := ReserveLoginAttempt(email, ip, dbName); err != nil || wait > 0 {
+		t.Errorf("login after reset requests: wait = %v, err = %v", wait, err)
+	}
+}
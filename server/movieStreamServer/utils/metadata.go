@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrMetadataNotFound = errors.New("no metadata found for this imdb_id")
+
+// MetadataProvider looks up catalog details for a movie by its IMDb ID. The
+// returned movie is a draft: fields the provider does not know, such as the
+// ranking, are left empty for an admin to fill in.
+type MetadataProvider interface {
+	LookupMovie(ctx context.Context, imdbId string) (*modelStructs.Movie, error)
+}
+
+// TMDBProvider fetches metadata from The Movie Database API using a v4 read
+// access token. BaseURL and ImageBaseURL default to the public endpoints.
+type TMDBProvider struct {
+	Token        string
+	BaseURL      string
+	ImageBaseURL string
+	HTTPClient   *http.Client
+}
+
+type tmdbMovie struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	PosterPath string `json:"poster_path"`
+	Genres     []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"genres"`
+	Videos struct {
+		Results []struct {
+			Site     string `json:"site"`
+			Type     string `json:"type"`
+			Key      string `json:"key"`
+			Official bool   `json:"official"`
+		} `json:"results"`
+	} `json:"videos"`
+}
+
+func (p TMDBProvider) LookupMovie(ctx context.Context, imdbId string) (*modelStructs.Movie, error) {
+	var found struct {
+		MovieResults []struct {
+			ID int `json:"id"`
+		} `json:"movie_results"`
+	}
+	if err := p.get(ctx, "/find/"+url.PathEscape(imdbId), url.Values{"external_source": {"imdb_id"}}, &found); err != nil {
+		return nil, err
+	}
+	if len(found.MovieResults) == 0 {
+		return nil, ErrMetadataNotFound
+	}
+
+	var details tmdbMovie
+	path := fmt.Sprintf("/movie/%d", found.MovieResults[0].ID)
+	if err := p.get(ctx, path, url.Values{"append_to_response": {"videos"}}, &details); err != nil {
+		return nil, err
+	}
+
+	movie := &modelStructs.Movie{
+		ImdbID: imdbId,
+		Title:  details.Title,
+		Genre:  make([]modelStructs.Genre, 0, len(details.Genres)),
+	}
+	if details.PosterPath != "" {
+		movie.PosterPath = p.imageBaseURL() + details.PosterPath
+	}
+	for _, genre := range details.Genres {
+		movie.Genre = append(movie.Genre, modelStructs.Genre{GenreID: genre.ID, GenreName: genre.Name})
+	}
+
+	// Prefer an official YouTube trailer, then any YouTube trailer.
+	for _, official := range []bool{true, false} {
+		for _, video := range details.Videos.Results {
+			if video.Site == "YouTube" && video.Type == "Trailer" && (video.Official || !official) {
+				movie.YouTubeID = video.Key
+				break
+			}
+		}
+		if movie.YouTubeID != "" {
+			break
+		}
+	}
+	return movie, nil
+}
+
+func (p TMDBProvider) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.themoviedb.org/3"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Accept", "application/json")
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrMetadataNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("TMDB returned %d: %s", res.StatusCode, body)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (p TMDBProvider) imageBaseURL() string {
+	if p.ImageBaseURL != "" {
+		return strings.TrimSuffix(p.ImageBaseURL, "/")
+	}
+	return "https://image.tmdb.org/t/p/w500"
+}
+
+// FixtureProvider serves metadata from a local NDJSON file of movies, the
+// same shape GET /export/movies writes. It is meant for development and
+// offline demos.
+type FixtureProvider struct {
+	Path string
+}
+
+func (p FixtureProvider) LookupMovie(ctx context.Context, imdbId string) (*modelStructs.Movie, error) {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var movie modelStructs.Movie
+		if err := json.Unmarshal([]byte(line), &movie); err != nil {
+			return nil, fmt.Errorf("fixture %s: %v", p.Path, err)
+		}
+		if movie.ImdbID == imdbId {
+			movie.ID = primitive.NilObjectID
+			return &movie, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrMetadataNotFound
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+// tmdbServer fakes the two TMDB endpoints LookupMovie calls. findResults is
+// the body of /find, details the body of /movie/550, and status, if set,
+// answers every request instead.
+func tmdbServer(t *testing.T, findResults, details string, status int) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /find/{imdb_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("external_source") != "imdb_id" {
+			t.Errorf("find: external_source = %q", r.URL.Query().Get("external_source"))
+		}
+		w.Write([]byte(findResults))
+	})
+	mux.HandleFunc("GET /movie/550", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("append_to_response") != "videos" {
+			t.Errorf("movie: append_to_response = %q", r.URL.Query().Get("append_to_response"))
+		}
+		w.Write([]byte(details))
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if status != 0 {
+			http.Error(w, "upstream failure", status)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTMDBProvider(t *testing.T) {
+	const found = `{"movie_results":[{"id":550}]}`
+
+	tests := []struct {
+		name     string
+		find     string
+		details  string
+		status   int
+		want     *modelStructs.Movie
+		wantErr  error
+		anyError bool
+	}{
+		{
+			name: "full details",
+			find: found,
+			details: `{"id":550,"title":"Fight Club","poster_path":"/p.jpg",
+				"genres":[{"id":18,"name":"Drama"}],
+				"videos":{"results":[
+					{"site":"Vimeo","type":"Trailer","key":"vimeo","official":true},
+					{"site":"YouTube","type":"Trailer","key":"fan","official":false},
+					{"site":"YouTube","type":"Teaser","key":"teaser","official":true},
+					{"site":"YouTube","type":"Trailer","key":"official","official":true}
+				]}}`,
+			want: &modelStructs.Movie{
+				ImdbID: "tt0137523", Title: "Fight Club", PosterPath: "https://images.example/p.jpg", YouTubeID: "official",
+				Genre: []modelStructs.Genre{{GenreID: 18, GenreName: "Drama"}},
+			},
+		},
+		{
+			name: "unofficial trailer, no poster",
+			find: found,
+			details: `{"id":550,"title":"Fight Club","genres":[],
+				"videos":{"results":[{"site":"YouTube","type":"Trailer","key":"fan","official":false}]}}`,
+			want: &modelStructs.Movie{ImdbID: "tt0137523", Title: "Fight Club", YouTubeID: "fan", Genre: []modelStructs.Genre{}},
+		},
+		{name: "no match", find: `{"movie_results":[]}`, wantErr: ErrMetadataNotFound},
+		{name: "upstream 404", status: http.StatusNotFound, wantErr: ErrMetadataNotFound},
+		{name: "upstream failure", status: http.StatusInternalServerError, anyError: true},
+		{name: "bad JSON", find: found, details: `{"title":`, anyError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tmdbServer(t, tt.find, tt.details, tt.status)
+			provider := TMDBProvider{Token: "token", BaseURL: server.URL + "/", ImageBaseURL: "https://images.example/", HTTPClient: server.Client()}
+
+			got, err := provider.LookupMovie(context.Background(), "tt0137523")
+			if tt.anyError {
+				if err == nil || errors.Is(err, ErrMetadataNotFound) {
+					t.Fatalf("LookupMovie error = %v, want a provider error", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LookupMovie error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupMovie = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixtureProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movies.ndjson")
+	fixture := `{"_id":"000000000000000000000001","imdb_id":"tt1","title":"One"}
+
+{"imdb_id":"tt2","title":"Two"}
+`
+	if err := os.WriteFile(path, []byte(fixture), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := FixtureProvider{Path: path}
+
+	movie, err := provider.LookupMovie(context.Background(), "tt1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "One" || !movie.ID.IsZero() {
+		t.Errorf("LookupMovie(tt1) = %+v", movie)
+	}
+
+	if _, err := provider.LookupMovie(context.Background(), "tt3"); err != ErrMetadataNotFound {
+		t.Errorf("LookupMovie(tt3) error = %v, want %v", err, ErrMetadataNotFound)
+	}
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	LoginChallengeExpiry = 5 * time.Minute
+	maxChallengeAttempts = 5
+	RecoveryCodeCount    = 10
+)
+
+var ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+
+func BeginTOTPEnrollment(userId, secret, dbName string) error {
+	return setUserFields(userId, bson.M{"$set": bson.M{"totp_pending_secret": secret}}, dbName)
+}
+
+func EnableTOTP(userId, secret string, step int64, recoveryHashes []string, dbName string) error {
+	return setUserFields(userId, bson.M{
+		"$set": bson.M{
+			"totp_enabled":   true,
+			"totp_secret":    secret,
+			"totp_last_step": step,
+			"recovery_codes": recoveryHashes,
+		},
+		"$unset": bson.M{"totp_pending_secret": ""},
+	}, dbName)
+}
+
+func DisableTOTP(userId, dbName string) error {
+	return setUserFields(userId, bson.M{
+		"$set":   bson.M{"totp_enabled": false, "totp_last_step": 0},
+		"$unset": bson.M{"totp_secret": "", "totp_pending_secret": "", "recovery_codes": ""},
+	}, dbName)
+}
+
+func setUserFields(userId string, update bson.M, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("users", dbName)
+	_, err := collection.UpdateOne(ctx, bson.M{"user_id": userId}, update)
+	return err
+}
+
+// UseTOTPStep records step as the last accepted TOTP step. It reports false
+// if an equal or later step was already used, which makes each code single
+// use even across concurrent requests.
+func UseTOTPStep(userId string, step int64, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("users", dbName)
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userId, "totp_last_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes the matching recovery code, reporting false if the
+// user has no such unused code.
+func UseRecoveryCode(userId, code, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hash := HashRecoveryCode(code)
+
+	collection := database.OpenCollection("users", dbName)
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userId, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func CreateLoginChallenge(userId, deviceName, dbName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	collection := database.OpenCollection("login_challenges", dbName)
+	_, err = collection.InsertOne(ctx, modelStructs.LoginChallenge{
+		TokenHash:  HashToken(token),
+		UserID:     userId,
+		DeviceName: deviceName,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(LoginChallengeExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func GetLoginChallenge(token, dbName string) (*modelStructs.LoginChallenge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"token_hash": HashToken(token),
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+		"attempts":   bson.M{"$lt": maxChallengeAttempts},
+	}
+
+	var challenge modelStructs.LoginChallenge
+
+	collection := database.OpenCollection("login_challenges", dbName)
+	if err := collection.FindOne(ctx, filter).Decode(&challenge); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// RecordChallengeFailure counts a wrong second factor against the challenge;
+// GetLoginChallenge stops accepting it once the attempts run out.
+func RecordChallengeFailure(token, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_challenges", dbName)
+	_, err := collection.UpdateOne(ctx, bson.M{"token_hash": HashToken(token)}, bson.M{"$inc": bson.M{"attempts": 1}})
+	return err
+}
+
+// CompleteLoginChallenge deletes the challenge, reporting false if another
+// request already redeemed it.
+func CompleteLoginChallenge(token, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("login_challenges", dbName)
+	res, err := collection.DeleteOne(ctx, bson.M{"token_hash": HashToken(token)})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
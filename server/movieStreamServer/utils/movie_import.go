@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+const importBatchSize = 500
+
+// ErrInvalidImport marks errors caused by the input as a whole, as opposed
+// to database failures.
+var ErrInvalidImport = errors.New("invalid import")
+
+var movieValidator = validator.New()
+
+// MovieCSVColumns are the columns understood in CSV imports and written by
+// CSV exports. Genres are written as id:name pairs separated by "|".
+var MovieCSVColumns = []string{
+	"imdb_id", "title", "poster_path", "youtube_id", "genre",
+	"ranking_value", "ranking_name", "admin_review", "admin_score",
+}
+
+// movieRow is one parsed input row. Err is set when the row could not be
+// parsed into a movie at all.
+type movieRow struct {
+	line  int
+	movie modelStructs.Movie
+	err   error
+}
+
+// ImportMovies reads movies in the given format, validates each row and
+// upserts the valid ones by imdb_id in batches. Rows that fail are reported
+// and skipped; an error is only returned when the input as a whole is
+// unreadable, wrapping ErrInvalidImport, or the database fails. The report
+// always covers the rows handled before the error.
+func ImportMovies(r io.Reader, format, dbName string) (modelStructs.ImportReport, error) {
+	report := modelStructs.ImportReport{Rows: make([]modelStructs.ImportRowResult, 0)}
+
+	var next func() (*movieRow, error)
+	switch format {
+	case FormatCSV:
+		reader, err := newCSVMovieReader(r)
+		if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		next = reader.next
+	case FormatNDJSON:
+		next = newNDJSONMovieReader(r).next
+	default:
+		return report, fmt.Errorf("%w: unsupported format %s", ErrInvalidImport, format)
+	}
+
+	genres, err := LoadGenres(dbName)
+	if err != nil {
+		return report, err
+	}
+	rankings, err := LoadRankings(dbName)
+	if err != nil {
+		return report, err
+	}
+
+	batch := make([]*movieRow, 0, importBatchSize)
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+
+		if row.err == nil {
+			row.err = movieValidator.Struct(row.movie)
+		}
+		if row.err == nil {
+			row.movie.Genre, row.err = resolveGenres(row.movie.Genre, genres)
+		}
+		if row.err == nil {
+			row.movie.Ranking, row.err = resolveRanking(row.movie.Ranking, rankings)
+		}
+		if row.err != nil {
+			report.Rejected++
+			report.Rows = append(report.Rows, modelStructs.ImportRowResult{
+				Line:   row.line,
+				ImdbID: row.movie.ImdbID,
+				Status: modelStructs.ImportRejected,
+				Error:  row.err.Error(),
+			})
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			if err := upsertMovies(batch, &report, dbName); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := upsertMovies(batch, &report, dbName); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func upsertMovies(batch []*movieRow, report *modelStructs.ImportReport, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	models := make([]mongo.WriteModel, 0, len(batch))
+	for _, row := range batch {
+		set := bson.M{
+			"updated_at":  now,
+			"imdb_id":     row.movie.ImdbID,
+			"title":       row.movie.Title,
+			"poster_path": row.movie.PosterPath,
+			"youtube_id":  row.movie.YouTubeID,
+			"genre":       row.movie.Genre,
+			"ranking":     row.movie.Ranking,
+		}
+		if row.movie.AdminReview != "" {
+			set["admin_review"] = row.movie.AdminReview
+		}
+		if row.movie.AdminScore != "" {
+			set["admin_score"] = row.movie.AdminScore
+		}
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imdb_id": row.movie.ImdbID}).
+			SetUpdate(bson.M{"$set": set, "$inc": bson.M{"version": 1}}).
+			SetUpsert(true))
+	}
+
+	collection := database.OpenCollection("movies", dbName)
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	failed := map[int]string{}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr.Message
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for i, row := range batch {
+		res := modelStructs.ImportRowResult{Line: row.line, ImdbID: row.movie.ImdbID}
+		if msg, ok := failed[i]; ok {
+			res.Status = modelStructs.ImportRejected
+			res.Error = msg
+			report.Rejected++
+		} else if _, ok := result.UpsertedIDs[int64(i)]; ok {
+			res.Status = modelStructs.ImportInserted
+			report.Inserted++
+		} else {
+			res.Status = modelStructs.ImportUpdated
+			report.Updated++
+		}
+		report.Rows = append(report.Rows, res)
+	}
+	return nil
+}
+
+type csvMovieReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVMovieReader(r io.Reader) (*csvMovieReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+
+	known := map[string]bool{}
+	for _, column := range MovieCSVColumns {
+		known[column] = true
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if !known[column] {
+			return nil, fmt.Errorf("unknown CSV column: %q", column)
+		}
+		columns[column] = i
+	}
+	return &csvMovieReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvMovieReader) next() (*movieRow, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &movieRow{line: parseErr.StartLine, err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := &movieRow{line: line}
+
+	get := func(column string) string {
+		if i, ok := c.columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row.movie = modelStructs.Movie{
+		ImdbID:      get("imdb_id"),
+		Title:       get("title"),
+		PosterPath:  get("poster_path"),
+		YouTubeID:   get("youtube_id"),
+		AdminReview: get("admin_review"),
+		AdminScore:  get("admin_score"),
+		Ranking:     modelStructs.Ranking{RankingName: get("ranking_name")},
+	}
+
+	if v := get("ranking_value"); v != "" {
+		if row.movie.Ranking.RankingValue, err = strconv.Atoi(v); err != nil {
+			row.err = fmt.Errorf("invalid ranking_value: %q", v)
+			return row, nil
+		}
+	}
+	if row.movie.Genre, err = ParseGenreList(get("genre")); err != nil {
+		row.err = err
+	}
+	return row, nil
+}
+
+// ParseGenreList parses the CSV genre column, e.g. "28:Action|18:Drama".
+func ParseGenreList(s string) ([]modelStructs.Genre, error) {
+	genres := make([]modelStructs.Genre, 0)
+	if s == "" {
+		return genres, nil
+	}
+
+	for _, part := range strings.Split(s, "|") {
+		id, name, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid genre %q, expected id:name", part)
+		}
+		genreId, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid genre id %q", id)
+		}
+		genres = append(genres, modelStructs.Genre{GenreID: genreId, GenreName: strings.TrimSpace(name)})
+	}
+	return genres, nil
+}
+
+// FormatGenreList is the inverse of ParseGenreList.
+func FormatGenreList(genres []modelStructs.Genre) string {
+	parts := make([]string, 0, len(genres))
+	for _, genre := range genres {
+		parts = append(parts, strconv.Itoa(genre.GenreID)+":"+genre.GenreName)
+	}
+	return strings.Join(parts, "|")
+}
+
+type ndjsonMovieReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONMovieReader(r io.Reader) *ndjsonMovieReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ndjsonMovieReader{scanner: scanner}
+}
+
+func (n *ndjsonMovieReader) next() (*movieRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+
+		data := bytes.TrimSpace(n.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &movieRow{line: n.line}
+		if err := json.Unmarshal(data, &row.movie); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
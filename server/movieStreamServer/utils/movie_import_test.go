@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+func TestParseGenreList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []modelStructs.Genre
+		wantErr bool
+	}{
+		{in: "", want: []modelStructs.Genre{}},
+		{in: "28:Action", want: []modelStructs.Genre{{GenreID: 28, GenreName: "Action"}}},
+		{in: "28:Action|18:Drama", want: []modelStructs.Genre{{GenreID: 28, GenreName: "Action"}, {GenreID: 18, GenreName: "Drama"}}},
+		{in: " 28 : Action | 18:Drama ", want: []modelStructs.Genre{{GenreID: 28, GenreName: "Action"}, {GenreID: 18, GenreName: "Drama"}}},
+		{in: "878:Science Fiction", want: []modelStructs.Genre{{GenreID: 878, GenreName: "Science Fiction"}}},
+		{in: "Action", wantErr: true},
+		{in: "x:Action", wantErr: true},
+		{in: "28:Action|", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseGenreList(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGenreList(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseGenreList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatGenreListRoundTrip(t *testing.T) {
+	genres := []modelStructs.Genre{{GenreID: 28, GenreName: "Action"}, {GenreID: 18, GenreName: "Drama"}}
+
+	s := FormatGenreList(genres)
+	if s != "28:Action|18:Drama" {
+		t.Fatalf("FormatGenreList = %q", s)
+	}
+	got, err := ParseGenreList(s)
+	if err != nil || !reflect.DeepEqual(got, genres) {
+		t.Errorf("ParseGenreList(FormatGenreList(g)) = %v, %v", got, err)
+	}
+}
+
+// readRows drains a movie reader, returning the rows and the error that
+// ended it, if it was not io.EOF.
+func readRows(next func() (*movieRow, error)) ([]*movieRow, error) {
+	var rows []*movieRow
+	for {
+		row, err := next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVMovieReader(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantHeader bool // header rejected
+		want       []movieRow
+	}{
+		{
+			name:  "full row",
+			input: "imdb_id,title,poster_path,youtube_id,genre,ranking_value,ranking_name,admin_review,admin_score\ntt0111161,The Shawshank Redemption,/p.jpg,yt1,18:Drama,1,Excellent,Great,9\n",
+			want: []movieRow{{line: 2, movie: modelStructs.Movie{
+				ImdbID: "tt0111161", Title: "The Shawshank Redemption", PosterPath: "/p.jpg", YouTubeID: "yt1",
+				Genre:       []modelStructs.Genre{{GenreID: 18, GenreName: "Drama"}},
+				Ranking:     modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"},
+				AdminReview: "Great", AdminScore: "9",
+			}}},
+		},
+		{
+			name:  "columns in any order with a BOM",
+			input: "\xef\xbb\xbftitle,imdb_id\nAlien,tt0078748\n",
+			want: []movieRow{{line: 2, movie: modelStructs.Movie{
+				ImdbID: "tt0078748", Title: "Alien", Genre: []modelStructs.Genre{},
+			}}},
+		},
+		{
+			name:  "quoted field spanning lines",
+			input: "imdb_id,title,admin_review\ntt1,\"A\nB\",x\ntt2,C,y\n",
+			want: []movieRow{
+				{line: 2, movie: modelStructs.Movie{ImdbID: "tt1", Title: "A\nB", AdminReview: "x", Genre: []modelStructs.Genre{}}},
+				{line: 4, movie: modelStructs.Movie{ImdbID: "tt2", Title: "C", AdminReview: "y", Genre: []modelStructs.Genre{}}},
+			},
+		},
+		{
+			name:       "unknown column",
+			input:      "imdb_id,rating\ntt1,5\n",
+			wantHeader: true,
+		},
+		{
+			name:       "empty input",
+			input:      "",
+			wantHeader: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := newCSVMovieReader(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantHeader {
+				t.Fatalf("newCSVMovieReader error = %v, want error %v", err, tt.wantHeader)
+			}
+			if err != nil {
+				return
+			}
+
+			rows, err := readRows(reader.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, row := range rows {
+				if row.err != nil {
+					t.Errorf("row %d: unexpected error %v", i, row.err)
+				}
+				if row.line != tt.want[i].line || !reflect.DeepEqual(row.movie, tt.want[i].movie) {
+					t.Errorf("row %d = line %d %+v, want line %d %+v", i, row.line, row.movie, tt.want[i].line, tt.want[i].movie)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVMovieReaderRowErrors(t *testing.T) {
+	input := "imdb_id,title,genre,ranking_value\n" +
+		"tt1,Good,28:Action,1\n" +
+		"tt2,Bad ranking,28:Action,one\n" +
+		"tt3,Bad genre,Action,1\n" +
+		"tt4,\"Bad \"quote\",28:Action,1\n"
+
+	reader, err := newCSVMovieReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := readRows(reader.next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line    int
+		wantErr bool
+	}{
+		{line: 2},
+		{line: 3, wantErr: true},
+		{line: 4, wantErr: true},
+		{line: 5, wantErr: true},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].line != w.line || (rows[i].err != nil) != w.wantErr {
+			t.Errorf("row %d = line %d err %v, want line %d error %v", i, rows[i].line, rows[i].err, w.line, w.wantErr)
+		}
+	}
+}
+
+func TestNDJSONMovieReader(t *testing.T) {
+	input := `{"imdb_id":"tt1","title":"One","genre":[{"genre_id":28,"genre_name":"Action"}],"ranking":{"ranking_value":1,"ranking_name":"Excellent"}}
+
+{"imdb_id":"tt2","title":
+{"imdb_id":"tt3","title":"Three"}
+`
+
+	rows, err := readRows(newNDJSONMovieReader(strings.NewReader(input)).next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line    int
+		imdbId  string
+		wantErr bool
+	}{
+		{line: 1, imdbId: "tt1"},
+		{line: 3, wantErr: true},
+		{line: 4, imdbId: "tt3"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.line != w.line || (row.err != nil) != w.wantErr || (!w.wantErr && row.movie.ImdbID != w.imdbId) {
+			t.Errorf("row %d = line %d %q err %v, want line %d %q error %v", i, row.line, row.movie.ImdbID, row.err, w.line, w.imdbId, w.wantErr)
+		}
+	}
+	if rows[0].movie.Ranking.RankingValue != 1 || len(rows[0].movie.Genre) != 1 {
+		t.Errorf("row 0 not fully decoded: %+v", rows[0].movie)
+	}
+}
+
+func TestNDJSONMovieReaderLineTooLong(t *testing.T) {
+	input := `{"imdb_id":"tt1","title":"` + strings.Repeat("x", 2<<20) + `"}` + "\n"
+
+	_, err := readRows(newNDJSONMovieReader(strings.NewReader(input)).next)
+	if err == nil {
+		t.Fatal("expected an error for a line over the limit")
+	}
+}
+
+func TestResolveRanking(t *testing.T) {
+	catalog := map[int]modelStructs.RankingLevel{
+		1:   {RankingValue: 1, RankingName: "Excellent"},
+		999: {RankingValue: 999, RankingName: "Not_Ranked", Hidden: true},
+	}
+
+	tests := []struct {
+		name    string
+		in      modelStructs.Ranking
+		want    modelStructs.Ranking
+		wantErr error
+	}{
+		{name: "known", in: modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"}, want: modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"}},
+		{name: "name taken from the scale", in: modelStructs.Ranking{RankingValue: 1, RankingName: "great"}, want: modelStructs.Ranking{RankingValue: 1, RankingName: "Excellent"}},
+		{name: "hidden level", in: modelStructs.Ranking{RankingValue: 999}, want: modelStructs.Ranking{RankingValue: 999, RankingName: "Not_Ranked"}},
+		{name: "unknown", in: modelStructs.Ranking{RankingValue: 7, RankingName: "Excellent"}, wantErr: ErrUnknownRanking},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRanking(tt.in, catalog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveRanking error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveRanking = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+)
+
+// testIssuer is a minimal OpenID Connect provider: it serves discovery and a
+// JWKS, remembers the PKCE challenge of the last authorization request and
+// answers the token endpoint with an ID token built from claims. tokenKid,
+// when set, signs the token under a key ID the JWKS does not list.
+type testIssuer struct {
+	*httptest.Server
+	key       *ecdsa.PrivateKey
+	kid       string
+	tokenKid  string
+	challenge string
+	claims    func(issuer string) jwt.MapClaims
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{key: key, kid: "test-key"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                iss.URL,
+			AuthorizationEndpoint: iss.URL + "/authorize",
+			TokenEndpoint:         iss.URL + "/token",
+			JWKSURI:               iss.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(modelStructs.JWKS{Keys: []modelStructs.JWK{{
+			Kty: "EC",
+			Kid: iss.kid,
+			Use: "sig",
+			Alg: "ES256",
+			Crv: "P-256",
+			X:   encode(key.X.FillBytes(make([]byte, 32))),
+			Y:   encode(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != iss.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, iss.claims(iss.URL))
+		token.Header["kid"] = iss.kid
+		if iss.tokenKid != "" {
+			token.Header["kid"] = iss.tokenKid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	baseClaims := func(issuer string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            issuer,
+			"sub":            "subject-1",
+			"aud":            "client-1",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          "nonce-1",
+			"email":          "user@example.com",
+			"email_verified": true,
+		}
+	}
+	with := func(changes jwt.MapClaims) func(string) jwt.MapClaims {
+		return func(issuer string) jwt.MapClaims {
+			claims := baseClaims(issuer)
+			for k, v := range changes {
+				claims[k] = v
+			}
+			return claims
+		}
+	}
+
+	tests := []struct {
+		name         string
+		claims       func(string) jwt.MapClaims
+		code         string
+		verifier     string
+		kid          string
+		wantErr      string
+		wantVerified bool
+	}{
+		{
+			name:         "verified email",
+			claims:       baseClaims,
+			wantVerified: true,
+		},
+		{
+			name:         "email_verified sent as a string",
+			claims:       with(jwt.MapClaims{"email_verified": "true"}),
+			wantVerified: true,
+		},
+		{
+			name:   "unverified email",
+			claims: with(jwt.MapClaims{"email_verified": false}),
+		},
+		{
+			name:   "missing email_verified",
+			claims: with(jwt.MapClaims{"email_verified": nil}),
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  with(jwt.MapClaims{"nonce": "other-nonce"}),
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:     "wrong PKCE verifier",
+			claims:   baseClaims,
+			verifier: "not-the-verifier",
+			wantErr:  "token endpoint returned 400",
+		},
+		{
+			name:    "unknown authorization code",
+			claims:  baseClaims,
+			code:    "bad-code",
+			wantErr: "token endpoint returned 400",
+		},
+		{
+			name:    "unknown signing key",
+			claims:  baseClaims,
+			kid:     "rotated-away",
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "wrong audience",
+			claims:  with(jwt.MapClaims{"aud": "someone-else"}),
+			wantErr: "invalid id_token",
+		},
+		{
+			name:    "wrong issuer",
+			claims:  with(jwt.MapClaims{"iss": "https://evil.example"}),
+			wantErr: "invalid id_token",
+		},
+		{
+			name:    "expired",
+			claims:  with(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+			wantErr: "invalid id_token",
+		},
+		{
+			name:    "no subject",
+			claims:  with(jwt.MapClaims{"sub": ""}),
+			wantErr: "no subject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iss := newTestIssuer(t)
+			iss.claims = tt.claims
+			iss.tokenKid = tt.kid
+
+			provider := &OIDCProvider{
+				Name:        "test",
+				Issuer:      iss.URL,
+				ClientID:    "client-1",
+				RedirectURL: "https://app.example/auth/test/callback",
+				Scopes:      []string{"openid", "email"},
+				HTTPClient:  iss.Client(),
+			}
+
+			verifier, err := MakePKCEVerifier()
+			if err != nil {
+				t.Fatal(err)
+			}
+			authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			parsed, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := parsed.Query()
+			if !strings.HasPrefix(authURL, iss.URL+"/authorize?") || query.Get("state") != "state-1" ||
+				query.Get("nonce") != "nonce-1" || query.Get("code_challenge_method") != "S256" {
+				t.Fatalf("unexpected authorization URL %s", authURL)
+			}
+			iss.challenge = query.Get("code_challenge")
+
+			code := "good-code"
+			if tt.code != "" {
+				code = tt.code
+			}
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+
+			claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if claims.Subject != "subject-1" || claims.Email != "user@example.com" {
+				t.Errorf("claims = %+v", claims)
+			}
+			if bool(claims.EmailVerified) != tt.wantVerified {
+				t.Errorf("EmailVerified = %v, want %v", claims.EmailVerified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestOIDCStateCookie(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetOIDCStateCookie(rec, CookieConfig{Secure: true, SameSite: http.SameSiteStrictMode}, "state-1")
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != OIDCStateCookie || cookie.Value == "state-1" || !cookie.HttpOnly || !cookie.Secure ||
+		cookie.SameSite != http.SameSiteLaxMode || cookie.Path != oidcCookiePath {
+		t.Fatalf("unexpected cookie %+v", cookie)
+	}
+
+	tests := []struct {
+		name    string
+		cookie  *http.Cookie
+		state   string
+		wantErr bool
+	}{
+		{name: "matching state", cookie: cookie, state: "state-1"},
+		{name: "different state", cookie: cookie, state: "state-2", wantErr: true},
+		{name: "no cookie", state: "state-1", wantErr: true},
+		{name: "raw state in cookie", cookie: &http.Cookie{Name: OIDCStateCookie, Value: "state-1"}, state: "state-1", wantErr: true},
+		{name: "empty state", cookie: &http.Cookie{Name: OIDCStateCookie, Value: ""}, state: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/test/callback?state="+tt.state, nil)
+			if tt.cookie != nil {
+				req.AddCookie(&http.Cookie{Name: tt.cookie.Name, Value: tt.cookie.Value})
+			}
+
+			err := CheckOIDCStateCookie(req, tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckOIDCStateCookie error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
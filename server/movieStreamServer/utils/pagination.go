@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorKind is the type of the sort value a cursor carries. Cursors come
+// from clients and their value ends up in a Mongo filter, so it must be a
+// plain value of the kind the sort field holds, never a document or array.
+type CursorKind int
+
+const (
+	CursorNone   CursorKind = iota // sorted by _id alone, no value
+	CursorString                   // a string, e.g. title
+	CursorInt                      // a whole number, e.g. ranking value
+	CursorNumber                   // any number, e.g. text score
+)
+
+func (k CursorKind) accepts(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return k == CursorNone
+	case string:
+		return k == CursorString
+	case float64:
+		return k == CursorNumber || (k == CursorInt && v == math.Trunc(v))
+	default:
+		return false
+	}
+}
+
+// PageCursor marks a position in a keyset-paginated listing: the sort value
+// and _id of the item at the page boundary. Before asks for the page that
+// precedes that item rather than the one that follows it.
+type PageCursor struct {
+	Sort   string      `json:"s"`
+	Value  interface{} `json:"v"`
+	ID     string      `json:"id"`
+	Before bool        `json:"b,omitempty"`
+}
+
+func EncodeCursor(cursor PageCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses an opaque cursor and checks it was issued for sort, so
+// a cursor cannot be replayed against a listing ordered differently, and that
+// its value is of the kind the sort field holds.
+func DecodeCursor(s, sort string, kind CursorKind) (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor PageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || !primitive.IsValidObjectID(cursor.ID) || !kind.accepts(cursor.Value) {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// PageSize reads the limit query parameter, falling back to the default for
+// missing or out-of-range values.
+func PageSize(r *http.Request) int64 {
+	limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// KeysetSort orders by field and then _id, both in the direction the page is
+// read in. Pages before a cursor are read backwards and reversed afterwards.
+func KeysetSort(field string, desc, before bool) primitive.D {
+	dir := 1
+	if desc != before {
+		dir = -1
+	}
+	if field == "_id" {
+		return primitive.D{{Key: "_id", Value: dir}}
+	}
+	return primitive.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// KeysetFilter matches the items strictly after cursor in the order given by
+// KeysetSort.
+func KeysetFilter(field string, desc bool, cursor PageCursor) bson.M {
+	op := "$gt"
+	if desc != cursor.Before {
+		op = "$lt"
+	}
+
+	id, _ := primitive.ObjectIDFromHex(cursor.ID)
+	if field == "_id" {
+		return bson.M{"_id": bson.M{op: id}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: cursor.Value}},
+		bson.M{field: cursor.Value, "_id": bson.M{op: id}},
+	}}
+}
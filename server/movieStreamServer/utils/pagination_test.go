@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name   string
+		cursor PageCursor
+		kind   CursorKind
+	}{
+		{name: "string value", cursor: PageCursor{Sort: "title", Value: "Alien", ID: id}, kind: CursorString},
+		{name: "zero value", cursor: PageCursor{Sort: "ranking", Value: float64(0), ID: id}, kind: CursorInt},
+		{name: "before", cursor: PageCursor{Sort: "-ranking", Value: float64(3), ID: id, Before: true}, kind: CursorInt},
+		{name: "fractional value", cursor: PageCursor{Sort: "-score", Value: 1.25, ID: id}, kind: CursorNumber},
+		{name: "no value", cursor: PageCursor{Sort: "added", ID: id}, kind: CursorNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor), tt.cursor.Sort, tt.kind)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.cursor) {
+				t.Errorf("DecodeCursor = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	valid := EncodeCursor(PageCursor{Sort: "title", Value: "Alien", ID: primitive.NewObjectID().Hex()})
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	withValue := func(sort, value string) string {
+		return encode(`{"s":"` + sort + `","v":` + value + `,"id":"` + primitive.NewObjectID().Hex() + `"}`)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		kind   CursorKind
+	}{
+		{name: "other sort", cursor: valid, sort: "-title", kind: CursorString},
+		{name: "not base64", cursor: "!!!", sort: "title", kind: CursorString},
+		{name: "not JSON", cursor: encode("title"), sort: "title", kind: CursorString},
+		{name: "bad object ID", cursor: encode(`{"s":"title","v":"Alien","id":"nope"}`), sort: "title", kind: CursorString},
+		{name: "missing ID", cursor: encode(`{"s":"title","v":"Alien"}`), sort: "title", kind: CursorString},
+		{name: "operator document", cursor: withValue("title", `{"$ne":null}`), sort: "title", kind: CursorString},
+		{name: "regex operator", cursor: withValue("title", `{"$regex":".*"}`), sort: "title", kind: CursorString},
+		{name: "array", cursor: withValue("title", `["Alien"]`), sort: "title", kind: CursorString},
+		{name: "number for a string field", cursor: withValue("title", `1`), sort: "title", kind: CursorString},
+		{name: "missing value", cursor: withValue("title", `null`), sort: "title", kind: CursorString},
+		{name: "string for a number field", cursor: withValue("ranking", `"1"`), sort: "ranking", kind: CursorInt},
+		{name: "fraction for an integer field", cursor: withValue("ranking", `1.5`), sort: "ranking", kind: CursorInt},
+		{name: "value for _id", cursor: withValue("added", `"Alien"`), sort: "added", kind: CursorNone},
+		{name: "document for a number field", cursor: withValue("-score", `{"$gt":0}`), sort: "-score", kind: CursorNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor, tt.sort, tt.kind); err != ErrInvalidCursor {
+				t.Errorf("DecodeCursor error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{query: "", want: DefaultPageSize},
+		{query: "limit=5", want: 5},
+		{query: "limit=100", want: MaxPageSize},
+		{query: "limit=101", want: MaxPageSize},
+		{query: "limit=0", want: DefaultPageSize},
+		{query: "limit=-3", want: DefaultPageSize},
+		{query: "limit=ten", want: DefaultPageSize},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/movies?"+tt.query, nil)
+		if got := PageSize(r); got != tt.want {
+			t.Errorf("PageSize(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestKeysetSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		desc   bool
+		before bool
+		want   primitive.D
+	}{
+		{name: "ascending", field: "title", want: primitive.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		{name: "descending", field: "title", desc: true, want: primitive.D{{Key: "title", Value: -1}, {Key: "_id", Value: -1}}},
+		{name: "ascending read backwards", field: "title", before: true, want: primitive.D{{Key: "title", Value: -1}, {Key: "_id", Value: -1}}},
+		{name: "descending read backwards", field: "title", desc: true, before: true, want: primitive.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		{name: "_id only", field: "_id", desc: true, want: primitive.D{{Key: "_id", Value: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeysetSort(tt.field, tt.desc, tt.before); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeysetSort = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	after := func(field, op string, value interface{}) bson.M {
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: value}},
+			bson.M{field: value, "_id": bson.M{op: id}},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		desc   bool
+		cursor PageCursor
+		want   bson.M
+	}{
+		{
+			name:   "ascending",
+			field:  "title",
+			cursor: PageCursor{Value: "Alien", ID: id.Hex()},
+			want:   after("title", "$gt", "Alien"),
+		},
+		{
+			name:   "descending",
+			field:  "title",
+			desc:   true,
+			cursor: PageCursor{Value: "Alien", ID: id.Hex()},
+			want:   after("title", "$lt", "Alien"),
+		},
+		{
+			name:   "ascending before",
+			field:  "ranking.ranking_value",
+			cursor: PageCursor{Value: float64(2), ID: id.Hex(), Before: true},
+			want:   after("ranking.ranking_value", "$lt", float64(2)),
+		},
+		{
+			name:   "descending before",
+			field:  "ranking.ranking_value",
+			desc:   true,
+			cursor: PageCursor{Value: float64(2), ID: id.Hex(), Before: true},
+			want:   after("ranking.ranking_value", "$gt", float64(2)),
+		},
+		{
+			name:   "_id only",
+			field:  "_id",
+			desc:   true,
+			cursor: PageCursor{ID: id.Hex()},
+			want:   bson.M{"_id": bson.M{"$lt": id}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeysetFilter(tt.field, tt.desc, tt.cursor); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeysetFilter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
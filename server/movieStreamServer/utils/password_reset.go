@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const PasswordResetExpiry = 30 * time.Minute
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// CreatePasswordReset issues a reset token for the user, superseding any
+// token issued before it. Only the token's hash is stored.
+func CreatePasswordReset(userId, dbName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	collection := database.OpenCollection("password_resets", dbName)
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": userId, "used_at": nil}); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	_, err = collection.InsertOne(ctx, modelStructs.PasswordReset{
+		TokenHash: HashToken(token),
+		UserID:    userId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(PasswordResetExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumePasswordReset marks the token as used and returns the user it was
+// issued to. A token can only be consumed once.
+func ConsumePasswordReset(token, dbName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"token_hash": HashToken(token),
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var reset modelStructs.PasswordReset
+
+	collection := database.OpenCollection("password_resets", dbName)
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"used_at": now}}).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", ErrInvalidResetToken
+		}
+		return "", err
+	}
+	return reset.UserID, nil
+}
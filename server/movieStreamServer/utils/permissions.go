@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"slices"
+)
+
+type Permission string
+
+const (
+	PermMovieWrite Permission = "movie:write"
+	PermReviewRank Permission = "review:rank"
+	PermUserAdmin  Permission = "user:admin"
+)
+
+// ServiceRole is the role of callers authenticated with an API key; their
+// permissions come from the key's scopes rather than the role.
+const ServiceRole = "SERVICE"
+
+var AllPermissions = []Permission{PermMovieWrite, PermReviewRank, PermUserAdmin}
+
+var rolePermissions = map[string][]Permission{
+	"ADMIN": {PermMovieWrite, PermReviewRank, PermUserAdmin},
+	"USER":  {},
+}
+
+func PermissionsForRole(role string) []Permission {
+	return rolePermissions[role]
+}
+
+func IsPermission(name string) bool {
+	return slices.Contains(AllPermissions, Permission(name))
+}
+
+func PermissionsFromScopes(scopes []string) []Permission {
+	perms := make([]Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		if IsPermission(scope) {
+			perms = append(perms, Permission(scope))
+		}
+	}
+	return perms
+}
+
+// ContextKey types the values AuthMiddleware stores on the request context.
+type ContextKey string
+
+const (
+	UserIDKey      ContextKey = "userID"
+	RoleKey        ContextKey = "role"
+	ClaimsKey      ContextKey = "claims"
+	PermissionsKey ContextKey = "permissions"
+)
+
+func HasPermission(ctx context.Context, perm Permission) bool {
+	perms, _ := ctx.Value(PermissionsKey).([]Permission)
+	return slices.Contains(perms, perm)
+}
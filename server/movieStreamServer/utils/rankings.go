@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrUnknownRanking = errors.New("unknown ranking")
+	ErrRankingInUse   = errors.New("ranking is still used by movies")
+)
+
+// LoadRankings returns the ranking scale keyed by ranking value.
+func LoadRankings(dbName string) (map[int]modelStructs.RankingLevel, error) {
+	levels, err := GetRankings(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[int]modelStructs.RankingLevel, len(levels))
+	for _, level := range levels {
+		catalog[level.RankingValue] = level
+	}
+	return catalog, nil
+}
+
+// ResolveRanking checks that ranking refers to a level of the scale by value
+// and returns it with the level's name, ignoring the name sent by the client.
+func ResolveRanking(ranking modelStructs.Ranking, dbName string) (modelStructs.Ranking, error) {
+	catalog, err := LoadRankings(dbName)
+	if err != nil {
+		return modelStructs.Ranking{}, err
+	}
+	return resolveRanking(ranking, catalog)
+}
+
+func resolveRanking(ranking modelStructs.Ranking, catalog map[int]modelStructs.RankingLevel) (modelStructs.Ranking, error) {
+	level, ok := catalog[ranking.RankingValue]
+	if !ok {
+		return modelStructs.Ranking{}, fmt.Errorf("%w: %d", ErrUnknownRanking, ranking.RankingValue)
+	}
+	return modelStructs.Ranking{RankingValue: level.RankingValue, RankingName: level.RankingName}, nil
+}
+
+// UpdateRanking applies update to the level with rankingValue. A new name is
+// copied into every movie ranked at that level so none are left pointing at
+// a name the scale no longer has.
+func UpdateRanking(rankingValue int, update modelStructs.RankingLevelUpdate, dbName string) (*modelStructs.RankingLevel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	set := bson.M{}
+	if update.RankingName != nil {
+		set["ranking_name"] = *update.RankingName
+	}
+	if update.Order != nil {
+		set["order"] = *update.Order
+	}
+	if update.Hidden != nil {
+		set["hidden"] = *update.Hidden
+	}
+
+	var level modelStructs.RankingLevel
+
+	collection := database.OpenCollection("rankings", dbName)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"ranking_value": rankingValue}, bson.M{"$set": set}, opts).Decode(&level); err != nil {
+		return nil, err
+	}
+
+	if update.RankingName != nil {
+		if _, err := database.OpenCollection("movies", dbName).UpdateMany(ctx,
+			bson.M{"ranking.ranking_value": rankingValue},
+			bson.M{
+				"$set": bson.M{"ranking.ranking_name": level.RankingName, "updated_at": time.Now().UTC()},
+				"$inc": bson.M{"version": 1},
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return &level, nil
+}
+
+// DeleteRanking removes a level from the scale, refusing while any movie is
+// still ranked at it.
+//
+// The level is deleted before movies are checked for it rather than after,
+// so writers resolving rankings from then on reject it, and a movie written
+// by one that resolved it just before shows up in the check, which puts the
+// level back.
+func DeleteRanking(rankingValue int, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("rankings", dbName)
+	deleted, err := collection.FindOneAndDelete(ctx, bson.M{"ranking_value": rankingValue}).Raw()
+	if err != nil {
+		return err
+	}
+
+	count, err := database.OpenCollection("movies", dbName).CountDocuments(ctx, bson.M{"ranking.ranking_value": rankingValue})
+	if err == nil && count > 0 {
+		err = fmt.Errorf("%w (%d movies)", ErrRankingInUse, count)
+	}
+	if err != nil {
+		if _, restoreErr := collection.InsertOne(ctx, deleted); restoreErr != nil && !mongo.IsDuplicateKeyError(restoreErr) {
+			return fmt.Errorf("%w; restoring ranking %d: %w", err, rankingValue, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
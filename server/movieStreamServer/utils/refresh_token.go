@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const RefreshTokenExpiry = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// HashToken returns the hex encoded SHA-256 digest of an opaque token so
+// that only digests are ever persisted.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func StoreRefreshToken(userId, familyId, refresh, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	token := modelStructs.RefreshToken{
+		TokenHash: HashToken(refresh),
+		UserID:    userId,
+		FamilyID:  familyId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(RefreshTokenExpiry),
+	}
+
+	collection := database.OpenCollection("refresh_tokens", dbName)
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// RotateRefreshToken marks the presented refresh token as used and returns
+// its record. Presenting a token that was already rotated means the token
+// leaked, so the whole session goes: every refresh token in its family, the
+// access tokens issued for it and the session record itself.
+func RotateRefreshToken(refresh, dbName string) (*modelStructs.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("refresh_tokens", dbName)
+	hash := HashToken(refresh)
+	now := time.Now().UTC()
+
+	filter := bson.M{
+		"token_hash": hash,
+		"rotated_at": nil,
+		"revoked_at": nil,
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var token modelStructs.RefreshToken
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"rotated_at": now}}).Decode(&token)
+	if err == nil {
+		return &token, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if err := collection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&token); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if token.RotatedAt != nil {
+		if err := RevokeRefreshFamily(token.FamilyID, dbName); err != nil {
+			return nil, err
+		}
+		if err := RevokeSessionTokens(token.FamilyID, dbName); err != nil {
+			return nil, err
+		}
+		if _, err := DeleteSession(token.FamilyID, token.UserID, dbName); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return nil, ErrInvalidRefreshToken
+}
+
+func RevokeRefreshFamily(familyId, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("refresh_tokens", dbName)
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"family_id": familyId, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}},
+	)
+	return err
+}
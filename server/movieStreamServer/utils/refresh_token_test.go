@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// testDatabase returns the name of a fresh database with the server's
+// indexes, dropped again when the test ends. Tests that need MongoDB are
+// skipped unless MONGODB_TEST_URI points at a server.
+func testDatabase(t *testing.T) string {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+	if database.Client == nil {
+		if err := database.DBinstance(uri); err != nil {
+			t.Fatalf("connecting to MongoDB: %v", err)
+		}
+	}
+
+	dbName := "movie_streamer_test_" + bson.NewObjectID().Hex()
+	if err := database.EnsureIndexes(dbName); err != nil {
+		t.Fatalf("creating indexes: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		database.Client.Database(dbName).Drop(ctx)
+	})
+	return dbName
+}
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "", want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{token: "abc", want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+
+	for _, tt := range tests {
+		if got := HashToken(tt.token); got != tt.want {
+			t.Errorf("HashToken(%q) = %s, want %s", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestMakeRefreshToken(t *testing.T) {
+	seen := map[string]bool{}
+	for range 100 {
+		token, err := MakeRefreshToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(token) != 64 {
+			t.Fatalf("len(token) = %d, want 64", len(token))
+		}
+		if seen[token] {
+			t.Fatalf("duplicate token %s", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	dbName := testDatabase(t)
+
+	const userId, familyId = "user-1", "session-1"
+
+	if err := CreateSession(modelStructs.Session{SessionID: familyId, UserID: userId, ExpiresAt: time.Now().Add(time.Hour)}, dbName); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreRefreshToken(userId, familyId, "first", dbName); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreRefreshToken(userId, familyId, "second", dbName); err != nil {
+		t.Fatal(err)
+	}
+
+	// The steps run in order against the same family: rotating "first"
+	// succeeds once, presenting it again is reuse and takes the whole
+	// session down, including the not yet rotated "second".
+	steps := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "first use rotates", token: "first"},
+		{name: "unknown token", token: "never-issued", wantErr: ErrInvalidRefreshToken},
+		{name: "reuse is detected", token: "first", wantErr: ErrRefreshTokenReused},
+		{name: "family is revoked", token: "second", wantErr: ErrInvalidRefreshToken},
+	}
+
+	for _, step := range steps {
+		token, err := RotateRefreshToken(step.token, dbName)
+		if err != step.wantErr {
+			t.Fatalf("%s: RotateRefreshToken error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if err == nil && (token.UserID != userId || token.FamilyID != familyId) {
+			t.Fatalf("%s: token = %+v", step.name, token)
+		}
+	}
+
+	if _, err := GetSession(familyId, dbName); err == nil {
+		t.Error("session still exists after reuse")
+	}
+
+	claims := &AccessTokenClaims{SessionID: familyId}
+	claims.ID = "jti-1"
+	claims.Subject = userId
+	revoked, err := IsTokenRevoked(claims, dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revoked {
+		t.Error("access tokens of the session are not revoked after reuse")
+	}
+}
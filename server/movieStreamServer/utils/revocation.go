@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func RevokeAccessToken(claims *AccessTokenClaims, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().UTC().Add(AccessTokenExpiry)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	collection := database.OpenCollection("revoked_tokens", dbName)
+	_, err := collection.InsertOne(ctx, modelStructs.RevokedToken{
+		TokenID:   claims.ID,
+		RevokedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	})
+	return err
+}
+
+func RevokeSessionTokens(sessionId, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	collection := database.OpenCollection("revoked_tokens", dbName)
+	_, err := collection.InsertOne(ctx, modelStructs.RevokedToken{
+		SessionID: sessionId,
+		RevokedAt: now,
+		ExpiresAt: now.Add(AccessTokenExpiry),
+	})
+	return err
+}
+
+// RevokeUserTokens invalidates every access token issued to the user so far,
+// e.g. after a password change or when an admin disables the account.
+// Token iat claims only have second precision, so revoked_at is truncated to
+// the second as well: tokens issued within the second of the revocation,
+// such as the one from logging in with a just-reset password, stay valid.
+func RevokeUserTokens(userId, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	collection := database.OpenCollection("revoked_tokens", dbName)
+	_, err := collection.InsertOne(ctx, modelStructs.RevokedToken{
+		UserID:    userId,
+		RevokedAt: now.Truncate(time.Second),
+		ExpiresAt: now.Add(AccessTokenExpiry),
+	})
+	return err
+}
+
+func IsTokenRevoked(claims *AccessTokenClaims, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conditions := bson.A{bson.M{"jti": claims.ID}}
+	if claims.SessionID != "" {
+		conditions = append(conditions, bson.M{"session_id": claims.SessionID})
+	}
+	if claims.IssuedAt != nil {
+		conditions = append(conditions, bson.M{
+			"user_id":    claims.Subject,
+			"revoked_at": bson.M{"$gte": claims.IssuedAt.Time.Add(time.Second)},
+		})
+	}
+
+	collection := database.OpenCollection("revoked_tokens", dbName)
+	count, err := collection.CountDocuments(ctx, bson.M{"$or": conditions})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestIsTokenRevokedByUser(t *testing.T) {
+	dbName := testDatabase(t)
+
+	now := time.Now().Truncate(time.Second)
+	if err := RevokeUserTokens("user-1", dbName); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		subject  string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "issued before the revocation", subject: "user-1", issuedAt: now.Add(-2 * time.Second), want: true},
+		{name: "issued after the revocation", subject: "user-1", issuedAt: now.Add(2 * time.Second), want: false},
+		{name: "other user", subject: "user-2", issuedAt: now.Add(-2 * time.Second), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &AccessTokenClaims{}
+			claims.ID = "jti-" + tt.name
+			claims.Subject = tt.subject
+			claims.IssuedAt = jwt.NewNumericDate(tt.issuedAt)
+
+			got, err := IsTokenRevoked(claims, dbName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsTokenRevoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/database"
+	"github.com/official-taufiq/movie-streamer/server/movieStreamServer/modelStructs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func CreateSession(session modelStructs.Session, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("sessions", dbName)
+	_, err := collection.InsertOne(ctx, session)
+	return err
+}
+
+func GetSession(sessionId, dbName string) (*modelStructs.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var session modelStructs.Session
+
+	collection := database.OpenCollection("sessions", dbName)
+	if err := collection.FindOne(ctx, bson.M{"session_id": sessionId}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records activity on a session and pushes its expiry out to
+// match the lifetime of the refresh token just issued for it.
+func TouchSession(sessionId, dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	collection := database.OpenCollection("sessions", dbName)
+	_, err := collection.UpdateOne(ctx, bson.M{"session_id": sessionId}, bson.M{
+		"$set": bson.M{
+			"last_seen_at": now,
+			"expires_at":   now.Add(RefreshTokenExpiry),
+		},
+	})
+	return err
+}
+
+func GetUserSessions(userId, dbName string) ([]modelStructs.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sessions := make([]modelStructs.Session, 0)
+
+	collection := database.OpenCollection("sessions", dbName)
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userId}, options.Find().SetSort(bson.M{"last_seen_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession signs a device out: the session record is removed, its
+// refresh token family is revoked and so are any access tokens carrying its
+// session ID. It reports false if the user has no such session.
+func DeleteSession(sessionId, userId, dbName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := database.OpenCollection("sessions", dbName)
+	res, err := collection.DeleteOne(ctx, bson.M{"session_id": sessionId, "user_id": userId})
+	if err != nil {
+		return false, err
+	}
+	if res.DeletedCount == 0 {
+		return false, nil
+	}
+
+	if err := RevokeRefreshFamily(sessionId, dbName); err != nil {
+		return true, err
+	}
+	if err := RevokeSessionTokens(sessionId, dbName); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// DeleteUserSessions signs out every session of the user except keep, which
+// may be empty to sign out all of them.
+func DeleteUserSessions(userId, keep, dbName string) error {
+	sessions, err := GetUserSessions(userId, dbName)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.SessionID == keep {
+			continue
+		}
+		if _, err := DeleteSession(session.SessionID, userId, dbName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
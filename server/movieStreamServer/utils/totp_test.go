@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed used by the test vectors in RFC 4226 and
+// RFC 6238.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, Appendix D.
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range want {
+		if got := hotp(rfcSecret, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcSecret)
+
+	// RFC 6238, Appendix B, SHA-1 rows truncated to six digits.
+	tests := []struct {
+		name     string
+		secret   string
+		unix     int64
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "t=59", unix: 59, code: "287082", wantStep: 1, wantOK: true},
+		{name: "t=1111111109", unix: 1111111109, code: "081804", wantStep: 37037036, wantOK: true},
+		{name: "t=1111111111", unix: 1111111111, code: "050471", wantStep: 37037037, wantOK: true},
+		{name: "t=1234567890", unix: 1234567890, code: "005924", wantStep: 41152263, wantOK: true},
+		{name: "t=2000000000", unix: 2000000000, code: "279037", wantStep: 66666666, wantOK: true},
+		{name: "t=20000000000", unix: 20000000000, code: "353130", wantStep: 666666666, wantOK: true},
+		{name: "previous step within skew", unix: 59 + 30, code: "287082", wantStep: 1, wantOK: true},
+		{name: "next step within skew", unix: 59 - 30, code: "287082", wantStep: 1, wantOK: true},
+		{name: "outside skew", unix: 59 + 60, code: "287082"},
+		{name: "replayed step", unix: 59, code: "287082", lastStep: 1},
+		{name: "wrong code", unix: 59, code: "287083"},
+		{name: "short code", unix: 59, code: "28708"},
+		{name: "lowercase secret", secret: strings.ToLower(secret), unix: 59, code: "287082", wantStep: 1, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := secret
+			if tt.secret != "" {
+				s = tt.secret
+			}
+
+			step, ok := ValidateTOTP(s, tt.code, tt.lastStep, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInvalidSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("not base32!", "287082", 0, time.Unix(59, 0)); ok {
+		t.Error("ValidateTOTP accepted an undecodable secret")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("JBSWY3DPEHPK3PXP", "user@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/"+TOTPIssuer+":user@example.com" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+
+	query := uri.Query()
+	for key, want := range map[string]string{
+		"secret":    "JBSWY3DPEHPK3PXP",
+		"issuer":    TOTPIssuer,
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes and %d hashes, want 10 each", len(codes), len(hashes))
+	}
+
+	for i, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q is not formatted as xxxx-xxxx", code)
+		}
+
+		// Users may retype codes in any case, with or without the dash.
+		for _, typed := range []string{code, strings.ToUpper(code), strings.ReplaceAll(code, "-", ""), " " + code + " "} {
+			if HashRecoveryCode(typed) != hashes[i] {
+				t.Errorf("HashRecoveryCode(%q) does not match the stored hash", typed)
+			}
+		}
+	}
+}